@@ -3,6 +3,7 @@ module arcedo/cli-todo
 go 1.25.7
 
 require (
+	golang.org/x/term v0.37.0 // direct
 	gorm.io/datatypes v1.2.7 // direct
 	gorm.io/driver/sqlite v1.6.0 // direct
 	gorm.io/gorm v1.31.1 // direct
@@ -15,6 +16,7 @@ require (
 	github.com/jinzhu/inflection v1.0.0 // indirect
 	github.com/jinzhu/now v1.1.5 // indirect
 	github.com/mattn/go-sqlite3 v1.14.22 // indirect
+	golang.org/x/sys v0.38.0 // indirect
 	golang.org/x/text v0.20.0 // indirect
 	gorm.io/driver/mysql v1.5.6 // indirect
 )
@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"arcedo/cli-todo/internal/config"
+)
+
+var errInvalidAlias = errors.New("invalid alias")
+
+func (c *CLI) aliasCommand() *command {
+	return &command{
+		name:    "alias",
+		summary: "Manage the aliases of the config file",
+		help: "An alias runs a command with some arguments, e.g. 'today = list today --sort priority'.\n" +
+			"The arguments given to the alias are added at the end. The names of the\n" +
+			"commands cannot be aliases.",
+		subcommands: []*command{
+			{
+				name:    "list",
+				summary: "List the aliases with the command they run",
+				setup: noFlags(func(ctx context.Context, args []string) error {
+					if len(c.config.Aliases) == 0 {
+						println(c.out, "No aliases found")
+						return nil
+					}
+					w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+					for _, s := range c.config.Settings() {
+						name, ok := strings.CutPrefix(s.Key, "aliases.")
+						if !ok {
+							continue
+						}
+						if c.builtin(name) {
+							s.Value += "  (ignored, it is a command)"
+						}
+						printf(w, "%s\t%s\n", name, s.Value)
+					}
+					w.Flush()
+					return nil
+				}),
+			},
+			{
+				name:    "add",
+				args:    "<name> <command> [arguments...]",
+				summary: "Add an alias, or replace it",
+				rawArgs: true,
+				setup: noFlags(func(ctx context.Context, args []string) error {
+					if c.config.Path == "" {
+						return &exitError{ExitFailure, errors.New("there is no config file")}
+					}
+					name, value := args[0], joinWords(args[1:])
+					if err := c.checkAlias(name, value); err != nil {
+						return err
+					}
+					return config.SetValue(c.config.Path, "aliases."+name, value)
+				}),
+			},
+			{
+				name:    "remove",
+				args:    "<names...>",
+				summary: "Remove aliases",
+				setup: noFlags(func(ctx context.Context, args []string) error {
+					if c.config.Path == "" {
+						return &exitError{ExitFailure, errors.New("there is no config file")}
+					}
+					for _, name := range args {
+						if err := config.Unset(c.config.Path, "aliases."+name); err != nil {
+							return err
+						}
+					}
+					return nil
+				}),
+			},
+		},
+	}
+}
+
+// builtin reports whether name is a command or one of their aliases
+func (c *CLI) builtin(name string) bool {
+	return findCommand(c.commands, name) != nil
+}
+
+// checkAlias reports why name cannot run value, if it cannot
+func (c *CLI) checkAlias(name, value string) error {
+	if c.builtin(name) {
+		return fmt.Errorf("%w: %s is a command", errInvalidAlias, name)
+	}
+	if strings.HasPrefix(name, "-") || strings.ContainsAny(name, " \t=#") {
+		return fmt.Errorf("%w: invalid name %q", errInvalidAlias, name)
+	}
+	aliases := maps.Clone(c.config.Aliases)
+	if aliases == nil {
+		aliases = map[string]string{}
+	}
+	aliases[name] = value
+	_, err := expandAliases([]string{program, name}, aliases, c.builtin)
+	return err
+}
+
+// expandAliases replaces the alias named by args[1] with its command,
+// which can be another alias. The aliases named like a command are
+// ignored, and an alias that leads back to itself is an error
+func expandAliases(args []string, aliases map[string]string, builtin func(name string) bool) ([]string, error) {
+	var chain []string
+	for len(args) > 1 {
+		name := args[1]
+		value, ok := aliases[name]
+		if !ok || builtin(name) {
+			return args, nil
+		}
+		chain = append(chain, name)
+		if slices.Contains(chain[:len(chain)-1], name) {
+			return nil, fmt.Errorf("%w: %s loops: %s", errInvalidAlias, chain[0], strings.Join(chain, " -> "))
+		}
+		words, err := splitWords(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %w", errInvalidAlias, name, err)
+		}
+		if len(words) == 0 {
+			return nil, fmt.Errorf("%w: %s runs no command", errInvalidAlias, name)
+		}
+		args = slices.Concat(args[:1], words, args[2:])
+	}
+	return args, nil
+}
+
+// splitWords splits s at the spaces, like a shell does for the
+// words that are not between single or double quotes
+func splitWords(s string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unclosed quote in %q", s)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+// joinWords is the opposite of splitWords, quoting
+// the words with spaces or quotes
+func joinWords(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		switch {
+		case strings.Contains(w, "'"):
+			quoted[i] = `"` + w + `"`
+		case w == "" || strings.ContainsAny(w, " \t\""):
+			quoted[i] = "'" + w + "'"
+		default:
+			quoted[i] = w
+		}
+	}
+	return strings.Join(quoted, " ")
+}
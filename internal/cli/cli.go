@@ -3,36 +3,146 @@ package cli
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
+	"strings"
 
+	"arcedo/cli-todo/internal/config"
+	"arcedo/cli-todo/internal/db"
 	"arcedo/cli-todo/internal/task"
 )
 
 type CLI struct {
 	taskService *task.Service
-	out         io.Writer
-	errOut      io.Writer
+	config      config.Config
+	// location is the database in use, shown by where
+	location db.Location
+	out      io.Writer
+	errOut   io.Writer
+	// edit opens a file in the user's editor
+	edit func(path string) error
+	// format writes the output, chosen with the global --output flag
+	format formatter
+	// commands is the registry of every command
+	commands []*command
 }
 
-func New(taskService *task.Service, out io.Writer, errOut io.Writer) *CLI {
-	return &CLI{
-		taskService,
-		out,
-		errOut,
+func New(taskService *task.Service, cfg config.Config, location db.Location, out io.Writer, errOut io.Writer) *CLI {
+	c := &CLI{
+		taskService: taskService,
+		config:      cfg,
+		location:    location,
+		out:         out,
+		errOut:      errOut,
+		edit:        runEditor,
+		format:      newTableFormatter(cfg, out),
 	}
+	c.commands = append(c.taskCommands(), c.projectCommand(), c.configCommand(), c.aliasCommand(), c.tuiCommand())
+	c.commands = append(c.commands, c.completionCommands()...)
+	c.commands = append(c.commands, &command{
+		name:    "where",
+		summary: "Show the database in use and why",
+		setup: noFlags(func(ctx context.Context, args []string) error {
+			printf(c.out, "%s\nfrom %s\n", c.location.Path, c.location.Source)
+			return nil
+		}),
+	}, &command{
+		name:    "help",
+		args:    "[command...]",
+		summary: "Show the help of a command",
+		setup:   noFlags(c.help),
+	})
+	return c
 }
 
-func (c *CLI) Run(ctx context.Context, args []string) {
+// Run runs the command of args and returns the exit code of the
+// process, printing the error that caused it, if any
+func (c *CLI) Run(ctx context.Context, args []string) int {
+	err := c.run(ctx, args)
+	var exit *exitError
+	if err != nil && !(errors.As(err, &exit) && exit.err == nil) {
+		println(c.errOut, err)
+	}
+	return exitCode(err)
+}
+
+func (c *CLI) run(ctx context.Context, args []string) error {
+	args, g, err := globalFlags(args)
+	if err != nil {
+		return &exitError{ExitUsage, err}
+	}
+	if args, err = expandAliases(args, c.config.Aliases, c.builtin); err != nil {
+		return err
+	}
+	// the aliases can have global flags too, the ones given by the user win
+	args, aliased, err := globalFlags(args)
+	if err != nil {
+		return &exitError{ExitUsage, err}
+	}
+	if g.output == "" {
+		g.output = aliased.output
+	}
+	if c.format, err = parseOutput(g.output); err != nil {
+		return &exitError{ExitUsage, err}
+	}
+	if _, table := c.format.(tableFormatter); table {
+		c.format = newTableFormatter(c.config, c.out)
+	}
+
 	if len(args) < 2 {
 		c.printUsage()
-		return
+		return nil
 	}
+	return c.dispatch(ctx, c.commands, "", args[1:])
+}
 
-	// I did a separated function so that in the future
-	// we want to add other entities is handled easly
-	c.runTask(ctx, args)
+// globals are the flags shared by every command,
+// empty when they are not given
+type globals struct {
+	output string
+	// db is the path of the database, which is opened before Run
+	db string
 }
 
-func (c *CLI) printUsage() {
-	println(c.out, "Usage: some usage here!")
+// DatabaseFlag returns the path given by the global --db flag of args,
+// if any, or of the words completed by __complete, but the last one being
+// typed. The errors of the global flags are reported by Run
+func DatabaseFlag(args []string) string {
+	if len(args) > 3 && args[1] == "__complete" && args[2] == "--" {
+		args = append([]string{args[0]}, args[3:len(args)-1]...)
+	}
+	_, g, _ := globalFlags(args)
+	return g.db
+}
+
+// globalFlags removes from args the flags shared by every command,
+// which can be given anywhere before a "--" argument
+func globalFlags(args []string) (rest []string, g globals, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			return append(rest, args[i:]...), g, nil
+		}
+		name, value, hasValue := strings.Cut(arg, "=")
+		var target *string
+		switch name {
+		case "-o", "-output", "--output":
+			target = &g.output
+		case "-db", "--db":
+			target = &g.db
+		default:
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return nil, globals{}, fmt.Errorf("flag needs an argument: %s", arg)
+			}
+			i++
+			value = args[i]
+		}
+		*target = value
+	}
+	return rest, g, nil
 }
@@ -3,10 +3,17 @@ package cli
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"arcedo/cli-todo/internal/config"
+	"arcedo/cli-todo/internal/db"
 	"arcedo/cli-todo/internal/task"
 )
 
@@ -24,18 +31,106 @@ func (m *mockRepo) Delete(ctx context.Context, ids []int) (int, error) {
 	return len(ids), nil
 }
 
-func (m *mockRepo) Get(ctx context.Context, ids []int, filter task.ListFilter) ([]task.Task, error) {
+func (m *mockRepo) Get(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error) {
 	now := time.Now()
-	return []task.Task{
+	tasks := []task.Task{
 		{ID: 1, Description: "Task 1", CompletedAt: nil},
-		{ID: 2, Description: "Task 2", CompletedAt: &now},
-	}, nil
+		{ID: 2, Description: "Task 2", CompletedAt: &now, Priority: task.High},
+	}
+	if opts.Where != nil {
+		tasks = slices.DeleteFunc(tasks, func(t task.Task) bool { return !opts.Where.Match(t, now) })
+	}
+	if filter == task.IDs {
+		tasks = slices.DeleteFunc(tasks, func(t task.Task) bool { return !slices.Contains(ids, int(t.ID)) })
+	}
+	return tasks, nil
+}
+
+func (m *mockRepo) Search(ctx context.Context, query task.SearchQuery, filter task.ListFilter) ([]task.Task, error) {
+	tasks, _ := m.Get(ctx, nil, filter, task.ListOptions{})
+	return slices.DeleteFunc(tasks, func(t task.Task) bool {
+		_, ok := query.Score(t.Description, t.Notes)
+		return !ok
+	}), nil
 }
 
 func (m *mockRepo) Complete(ctx context.Context, ids []int) (int, error) {
 	return len(ids), nil
 }
 
+func (m *mockRepo) Descendants(ctx context.Context, ids []int) ([]task.Task, error) {
+	parent := uint(1)
+	return []task.Task{{ID: 3, Description: "Subtask", ParentID: &parent}}, nil
+}
+
+func (m *mockRepo) AddDependencies(ctx context.Context, id int, blockers []int) error {
+	return nil
+}
+
+func (m *mockRepo) RemoveDependencies(ctx context.Context, id int, blockers []int) (int, error) {
+	return len(blockers), nil
+}
+
+func (m *mockRepo) Dependencies(ctx context.Context) ([]task.Dependency, error) {
+	return []task.Dependency{{TaskID: 2, BlockerID: 1}}, nil
+}
+
+func (m *mockRepo) AddTags(ctx context.Context, id int, tags []string) error {
+	return nil
+}
+
+func (m *mockRepo) RemoveTags(ctx context.Context, id int, tags []string) error {
+	return nil
+}
+
+func (m *mockRepo) Tags(ctx context.Context) ([]task.TagCount, error) {
+	return []task.TagCount{{Name: "groceries", Count: 2}, {Name: "work", Count: 0}}, nil
+}
+
+func (m *mockRepo) CreateProject(ctx context.Context, p *task.Project) error {
+	p.ID = 2
+	return nil
+}
+
+func (m *mockRepo) GetProject(ctx context.Context, name string) (task.Project, error) {
+	if name != "Home" {
+		return task.Project{}, task.ErrProjectNotFound
+	}
+	return task.Project{ID: 1, Name: name}, nil
+}
+
+func (m *mockRepo) GetProjects(ctx context.Context) ([]task.Project, error) {
+	return []task.Project{{ID: 1, Name: "Home"}}, nil
+}
+
+func (m *mockRepo) UpdateProject(ctx context.Context, p task.Project) error {
+	return nil
+}
+
+func (m *mockRepo) AssignProject(ctx context.Context, ids []int, projectID *uint) (int, error) {
+	return len(ids), nil
+}
+
+func (m *mockRepo) Uncomplete(ctx context.Context, ids []int) (int, error) {
+	return len(ids), nil
+}
+
+func (m *mockRepo) Update(ctx context.Context, t task.Task) error {
+	return nil
+}
+
+func (m *mockRepo) Restore(ctx context.Context, ids []int) (int, error) {
+	return len(ids), nil
+}
+
+func (m *mockRepo) Purge(ctx context.Context, ids []int) (int, error) {
+	return len(ids), nil
+}
+
+func (m *mockRepo) AddAnnotation(ctx context.Context, a *task.Annotation) error {
+	return nil
+}
+
 // ------------------------
 // Error repository (for testing errors)
 // ------------------------
@@ -50,14 +145,90 @@ func (m *errorRepo) Delete(ctx context.Context, ids []int) (int, error) {
 	return 0, errMock("delete failed")
 }
 
-func (m *errorRepo) Get(ctx context.Context, ids []int, filter task.ListFilter) ([]task.Task, error) {
+func (m *errorRepo) Get(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error) {
 	return nil, errMock("list failed")
 }
 
+func (m *errorRepo) Search(ctx context.Context, query task.SearchQuery, filter task.ListFilter) ([]task.Task, error) {
+	return nil, errMock("search failed")
+}
+
 func (m *errorRepo) Complete(ctx context.Context, ids []int) (int, error) {
 	return 0, errMock("complete failed")
 }
 
+// Descendants does not fail so the errors
+// of the cascading actions can be tested
+func (m *errorRepo) Descendants(ctx context.Context, ids []int) ([]task.Task, error) {
+	return nil, nil
+}
+
+func (m *errorRepo) AddDependencies(ctx context.Context, id int, blockers []int) error {
+	return errMock("add dependencies failed")
+}
+
+func (m *errorRepo) RemoveDependencies(ctx context.Context, id int, blockers []int) (int, error) {
+	return 0, errMock("remove dependencies failed")
+}
+
+// Dependencies does not fail so the errors
+// of completing can be tested
+func (m *errorRepo) Dependencies(ctx context.Context) ([]task.Dependency, error) {
+	return nil, nil
+}
+
+func (m *errorRepo) AddTags(ctx context.Context, id int, tags []string) error {
+	return errMock("add tags failed")
+}
+
+func (m *errorRepo) RemoveTags(ctx context.Context, id int, tags []string) error {
+	return errMock("remove tags failed")
+}
+
+func (m *errorRepo) Tags(ctx context.Context) ([]task.TagCount, error) {
+	return nil, errMock("tags failed")
+}
+
+func (m *errorRepo) CreateProject(ctx context.Context, p *task.Project) error {
+	return errMock("create project failed")
+}
+
+func (m *errorRepo) GetProject(ctx context.Context, name string) (task.Project, error) {
+	return task.Project{}, errMock("get project failed")
+}
+
+func (m *errorRepo) GetProjects(ctx context.Context) ([]task.Project, error) {
+	return nil, errMock("get projects failed")
+}
+
+func (m *errorRepo) UpdateProject(ctx context.Context, p task.Project) error {
+	return errMock("update project failed")
+}
+
+func (m *errorRepo) AssignProject(ctx context.Context, ids []int, projectID *uint) (int, error) {
+	return 0, errMock("assign project failed")
+}
+
+func (m *errorRepo) Uncomplete(ctx context.Context, ids []int) (int, error) {
+	return 0, errMock("uncomplete failed")
+}
+
+func (m *errorRepo) Update(ctx context.Context, t task.Task) error {
+	return errMock("update failed")
+}
+
+func (m *errorRepo) Restore(ctx context.Context, ids []int) (int, error) {
+	return 0, errMock("restore failed")
+}
+
+func (m *errorRepo) Purge(ctx context.Context, ids []int) (int, error) {
+	return 0, errMock("purge failed")
+}
+
+func (m *errorRepo) AddAnnotation(ctx context.Context, a *task.Annotation) error {
+	return errMock("add annotation failed")
+}
+
 // simple helper for error
 type errMock string
 
@@ -71,10 +242,23 @@ func newTestCLI(repo task.Repository) (*CLI, *bytes.Buffer, *bytes.Buffer) {
 	out := &bytes.Buffer{}
 	errOut := &bytes.Buffer{}
 	svc := task.NewService(repo)
-	c := New(svc, out, errOut)
+	cfg := config.Default()
+	cfg.Color = false
+	c := New(svc, cfg, db.Location{Path: "test.db", Source: "the test"}, out, errOut)
 	return c, out, errOut
 }
 
+// containsRow reports whether any line of the table
+// has the given fields, ignoring the column padding
+func containsRow(table, row string) bool {
+	for _, line := range strings.Split(table, "\n") {
+		if strings.Join(strings.Fields(line), " ") == row {
+			return true
+		}
+	}
+	return false
+}
+
 func TestCLI_PrintUsage(t *testing.T) {
 	c, out, _ := newTestCLI(&mockRepo{})
 	c.printUsage()
@@ -85,6 +269,159 @@ func TestCLI_PrintUsage(t *testing.T) {
 	}
 }
 
+func TestCLI_HelpCommand(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "help", "complete"}
+	c.Run(context.Background(), args)
+
+	got := out.String()
+	for _, want := range []string{"Usage: cli-todo complete [flags] <ids...>", "-recursive", "-force"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in help, got %q", want, got)
+		}
+	}
+}
+
+func TestCLI_CommandHelpFlag(t *testing.T) {
+	c, out, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "project", "assign", "--help"}
+	c.Run(context.Background(), args)
+
+	if !strings.Contains(out.String(), "Usage: cli-todo project assign <name> <ids...>") || errOut.Len() != 0 {
+		t.Errorf("expected help printed, got %q and %q", out.String(), errOut.String())
+	}
+}
+
+func TestCLI_CommandAlias(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "help", "reopen"}
+	c.Run(context.Background(), args)
+
+	if !strings.Contains(out.String(), "Usage: cli-todo uncomplete <ids...>") {
+		t.Errorf("expected the help of the aliased command, got %q", out.String())
+	}
+}
+
+func TestCLI_UnknownCommand(t *testing.T) {
+	c, _, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "lsit"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, `unknown command "lsit", did you mean "list"?`) {
+		t.Errorf("unexpected error output: %q", got)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	cmds := []*command{{name: "list"}, {name: "tag"}, {name: "tags"}, {name: "uncomplete", aliases: []string{"reopen"}}}
+	tests := map[string]string{
+		"lst":    `"list"`,
+		"tga":    `"tag"`,
+		"reopn":  `"uncomplete"`,
+		"unc":    `"uncomplete"`,
+		"tagz":   `"tag" or "tags"`,
+		"delete": "",
+	}
+	for name, want := range tests {
+		if got := strings.Join(suggest(name, cmds), " or "); got != want {
+			t.Errorf("%s: expected %q, got %q", name, want, got)
+		}
+	}
+}
+
+func TestCLI_ExitCodes(t *testing.T) {
+	tests := []struct {
+		name string
+		repo task.Repository
+		args []string
+		want int
+	}{
+		{"ok", &mockRepo{}, []string{"cli", "list"}, ExitOK},
+		{"usage", &mockRepo{}, []string{"cli", "lsit"}, ExitUsage},
+		{"usage arguments", &mockRepo{}, []string{"cli", "show"}, ExitUsage},
+		{"usage flag", &mockRepo{}, []string{"cli", "list", "--nope"}, ExitUsage},
+		{"usage ID", &mockRepo{}, []string{"cli", "remove", "one"}, ExitUsage},
+		{"validation", &mockRepo{}, []string{"cli", "new", "--priority", "urgent", "My Task"}, ExitValidation},
+		{"not found", &mockRepo{}, []string{"cli", "edit", "99", "Nothing"}, ExitNotFound},
+		{"storage", &errorRepo{}, []string{"cli", "list"}, ExitStorage},
+		{"storage create", &errorRepo{}, []string{"cli", "new", "My Task"}, ExitStorage},
+	}
+	for _, tt := range tests {
+		c, _, _ := newTestCLI(tt.repo)
+		if got := c.Run(context.Background(), tt.args); got != tt.want {
+			t.Errorf("%s: expected exit code %d, got %d", tt.name, tt.want, got)
+		}
+	}
+	if got := exitCode(errors.New("not a terminal")); got != ExitFailure {
+		t.Errorf("expected the unclassified errors to exit with %d, got %d", ExitFailure, got)
+	}
+}
+
+func TestCLI_TUICommandNotTerminal(t *testing.T) {
+	c, _, errOut := newTestCLI(&mockRepo{})
+
+	code := c.Run(context.Background(), []string{"cli", "tui"})
+
+	if code != ExitFailure || !strings.Contains(errOut.String(), "not a terminal") {
+		t.Errorf("expected exit code %d with the error, got %d %q", ExitFailure, code, errOut.String())
+	}
+}
+
+func TestCLI_ResultExitCode(t *testing.T) {
+	c, _, _ := newTestCLI(&mockRepo{})
+	c.format = tableFormatter{}
+
+	tests := []struct {
+		affected int
+		ids      []int
+		want     int
+	}{
+		{3, []int{1, 2, 99}, ExitOK},
+		{1, []int{1, 2, 99}, ExitPartial},
+		{0, []int{1, 2, 99}, ExitPartial},
+		{0, []int{97, 98, 99}, ExitNotFound},
+	}
+	for _, tt := range tests {
+		err := c.result(context.Background(), result{Action: "deleted", Affected: tt.affected, Total: 3}, tt.ids)
+		if got := exitCode(err); got != tt.want {
+			t.Errorf("%d of %v: expected exit code %d, got %d", tt.affected, tt.ids, tt.want, got)
+		}
+	}
+}
+
+func TestCLI_WhereCommand(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "--db", "test.db", "where"}
+	if code := c.Run(context.Background(), args); code != ExitOK {
+		t.Fatalf("expected exit code 0, got %d", code)
+	}
+
+	want := "test.db\nfrom the test\n"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
+
+func TestDatabaseFlag(t *testing.T) {
+	tests := map[string][]string{
+		"a.db": {"cli", "--db", "a.db", "list"},
+		"b.db": {"cli", "list", "-db=b.db"},
+		"":     {"cli", "new", "--", "--db", "c.db"},
+		"d.db": {"cli", "__complete", "--", "--db", "d.db", "complete", ""},
+	}
+	for want, args := range tests {
+		if got := DatabaseFlag(args); got != want {
+			t.Errorf("%v: expected %q, got %q", args, want, got)
+		}
+	}
+}
+
 func TestCLI_NewCommand(t *testing.T) {
 	c, out, _ := newTestCLI(&mockRepo{})
 
@@ -97,6 +434,82 @@ func TestCLI_NewCommand(t *testing.T) {
 	}
 }
 
+func TestCLI_NewCommandPriority(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "new", "My Task", "--priority", "high"}
+	c.Run(context.Background(), args)
+
+	got := out.String()
+	if !strings.Contains(got, "high") || !strings.Contains(got, "My Task") {
+		t.Errorf("expected prioritized task printed, got %q", got)
+	}
+}
+
+func TestCLI_NewCommandTags(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "new", "Buy milk +Groceries", "--tag", "home"}
+	c.Run(context.Background(), args)
+
+	got := out.String()
+	if !strings.Contains(got, "Buy milk  +home +groceries") {
+		t.Errorf("expected tagged task printed, got %q", got)
+	}
+}
+
+func TestCLI_NewCommandQuickAdd(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	c.Run(context.Background(), []string{"cli", "new", "Call Bob !high +work"})
+	if got := out.String(); !strings.Contains(got, "high") || !strings.Contains(got, "Call Bob  +work") {
+		t.Errorf("expected the fields read from the description, got %q", got)
+	}
+
+	out.Reset()
+	c.Run(context.Background(), []string{"cli", "new", "--raw", "Call Bob !high +work"})
+	if got := out.String(); !strings.Contains(got, "Call Bob !high +work") {
+		t.Errorf("expected the raw description, got %q", got)
+	}
+}
+
+func TestCLI_NewCommandEditorRaw(t *testing.T) {
+	c, out, errOut := newTestCLI(&mockRepo{})
+	c.edit = func(path string) error {
+		return os.WriteFile(path, []byte("Description: Call Bob friday !high\nPriority: low\nDue:\n"), 0o600)
+	}
+
+	c.Run(context.Background(), []string{"cli", "new", "--edit", "--priority", "low"})
+
+	if !containsRow(out.String(), "0 · low 01/01/0001 00:00 Call Bob friday !high") {
+		t.Errorf("expected the document fields and the description as written, got %q (%q)", out.String(), errOut.String())
+	}
+}
+
+func TestCLI_NewCommandRecurrence(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "new", "--every", "thu,Monday", "Report"}
+	c.Run(context.Background(), args)
+
+	got := out.String()
+	if !strings.Contains(got, "mon,thu") {
+		t.Errorf("expected recurrence printed, got %q", got)
+	}
+}
+
+func TestCLI_NewCommandInvalidPriority(t *testing.T) {
+	c, _, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "new", "--priority", "urgent", "My Task"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, "invalid priority") {
+		t.Errorf("expected priority error, got %q", got)
+	}
+}
+
 func TestCLI_RemoveCommand(t *testing.T) {
 	c, out, _ := newTestCLI(&mockRepo{})
 
@@ -116,63 +529,75 @@ func TestCLI_ListCommand(t *testing.T) {
 	c.Run(context.Background(), args)
 
 	got := out.String()
-	if !strings.Contains(got, "[ ] 1 - Task 1") || !strings.Contains(got, "[x] 2 - Task 2") {
+	if !containsRow(got, "1 · 01/01/0001 00:00 Task 1") || !containsRow(got, "2 ✓ high 01/01/0001 00:00 Task 2") {
 		t.Errorf("unexpected output:\n%s", got)
 	}
 }
 
-func TestCLI_CompleteCommand(t *testing.T) {
+func TestCLI_ListCommandInvalidSort(t *testing.T) {
+	c, _, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "list", "--sort", "colour"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, "invalid sort field") {
+		t.Errorf("expected sort error, got %q", got)
+	}
+}
+
+func TestCLI_PrioritizeCommand(t *testing.T) {
 	c, out, _ := newTestCLI(&mockRepo{})
 
-	args := []string{"cli", "complete", "1", "2"}
+	args := []string{"cli", "prioritize", "1", "low"}
 	c.Run(context.Background(), args)
 
 	got := out.String()
-	if !strings.Contains(got, "2 of 2 tasks successfully completed") {
-		t.Errorf("unexpected output: %q", got)
+	if !strings.Contains(got, "low") || !strings.Contains(got, "Task 1") {
+		t.Errorf("expected prioritized task printed, got %q", got)
 	}
 }
 
-func TestCLI_InvalidIDs(t *testing.T) {
-	c, _, errOut := newTestCLI(&mockRepo{})
+func TestCLI_DueCommand(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
 
-	args := []string{"cli", "remove", "notanumber"}
+	args := []string{"cli", "due", "1", "2000-01-02"}
 	c.Run(context.Background(), args)
 
-	got := errOut.String()
-	if !strings.Contains(got, "failed to parse ID") {
-		t.Errorf("expected parse error, got %q", got)
+	got := out.String()
+	if !strings.Contains(got, "02/01/2000 23:59 (overdue)") {
+		t.Errorf("expected overdue due date printed, got %q", got)
 	}
 }
 
-func TestCLI_NewCommandError(t *testing.T) {
-	c, _, errOut := newTestCLI(&errorRepo{})
+func TestCLI_DueCommandInvalidDate(t *testing.T) {
+	c, _, errOut := newTestCLI(&mockRepo{})
 
-	args := []string{"cli", "new", "My Task"}
+	args := []string{"cli", "due", "1", "someday"}
 	c.Run(context.Background(), args)
 
 	got := errOut.String()
-	if !strings.Contains(got, "create failed") {
-		t.Errorf("expected error printed, got %q", got)
+	if !strings.Contains(got, "invalid date") {
+		t.Errorf("expected date error, got %q", got)
 	}
 }
 
-func TestCLI_RemoveCommandError(t *testing.T) {
-	c, _, errOut := newTestCLI(&errorRepo{})
+func TestCLI_TagsCommand(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
 
-	args := []string{"cli", "remove", "1"}
+	args := []string{"cli", "tags"}
 	c.Run(context.Background(), args)
 
-	got := errOut.String()
-	if !strings.Contains(got, "delete failed") {
-		t.Errorf("expected error printed, got %q", got)
+	got := out.String()
+	if !containsRow(got, "groceries 2") || !containsRow(got, "work 0") {
+		t.Errorf("unexpected output:\n%s", got)
 	}
 }
 
-func TestCLI_ListCommandError(t *testing.T) {
+func TestCLI_TagCommandError(t *testing.T) {
 	c, _, errOut := newTestCLI(&errorRepo{})
 
-	args := []string{"cli", "list"}
+	args := []string{"cli", "tag", "1", "work"}
 	c.Run(context.Background(), args)
 
 	got := errOut.String()
@@ -181,14 +606,942 @@ func TestCLI_ListCommandError(t *testing.T) {
 	}
 }
 
-func TestCLI_CompleteCommandError(t *testing.T) {
-	c, _, errOut := newTestCLI(&errorRepo{})
+func TestCLI_CompleteCommand(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
 
-	args := []string{"cli", "complete", "1"}
+	args := []string{"cli", "complete", "--recursive", "--force", "1", "2"}
 	c.Run(context.Background(), args)
 
-	got := errOut.String()
-	if !strings.Contains(got, "complete failed") {
-		t.Errorf("expected error printed, got %q", got)
+	got := out.String()
+	if !strings.Contains(got, "2 of 2 tasks successfully completed") {
+		t.Errorf("unexpected output: %q", got)
+	}
+}
+
+func TestCLI_CompleteCommandOpenSubtasks(t *testing.T) {
+	c, out, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "complete", "--force", "1", "2"}
+	c.Run(context.Background(), args)
+
+	if got := out.String(); !strings.Contains(got, "1 of 2 tasks successfully completed") {
+		t.Errorf("unexpected output: %q", got)
+	}
+	if got := errOut.String(); !strings.Contains(got, "task 1 (1 open)") {
+		t.Errorf("expected open subtasks warning, got %q", got)
+	}
+}
+
+func TestCLI_CompleteCommandBlocked(t *testing.T) {
+	c, out, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "complete", "--recursive", "1", "2"}
+	c.Run(context.Background(), args)
+
+	if got := out.String(); !strings.Contains(got, "1 of 2 tasks successfully completed") {
+		t.Errorf("unexpected output: %q", got)
+	}
+	if got := errOut.String(); !strings.Contains(got, "task 2 (by 1)") || !strings.Contains(got, "--force") {
+		t.Errorf("expected blocked warning, got %q", got)
+	}
+}
+
+func TestCLI_BlockCommand(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "block", "2", "--by", "1"}
+	c.Run(context.Background(), args)
+
+	got := out.String()
+	if !strings.Contains(got, "task 2 successfully blocked by 1 tasks") {
+		t.Errorf("unexpected output: %q", got)
+	}
+}
+
+func TestCLI_BlockCommandCycle(t *testing.T) {
+	c, _, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "block", "1", "--by", "2"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, "dependency cycle: 1 -> 2 -> 1") {
+		t.Errorf("expected cycle error, got %q", got)
+	}
+}
+
+func TestCLI_ListTreeCommand(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "list", "--tree"}
+	c.Run(context.Background(), args)
+
+	got := out.String()
+	if !strings.Contains(got, "Task 1 [0/1]") {
+		t.Errorf("expected roll-up printed, got %q", got)
+	}
+}
+
+func TestPrintTree(t *testing.T) {
+	parent, child := uint(1), uint(2)
+	tasks := []task.Task{
+		{ID: 1, Description: "Root"},
+		{ID: 2, Description: "Child", ParentID: &parent},
+		{ID: 3, Description: "Grandchild", ParentID: &child},
+		{ID: 4, Description: "Other"},
+	}
+	out := &bytes.Buffer{}
+	tableFormatter{dateFormat: config.DefaultDateFormat}.printTree(out, tasks, map[uint]task.Progress{1: {Done: 1, Total: 2}})
+
+	lines := strings.Split(out.String(), "\n")
+	want := []string{"Root [1/2]", "  Child", "    Grandchild", "Other"}
+	for i, w := range want {
+		if !strings.HasSuffix(strings.TrimRight(lines[i+2], " "), w) {
+			t.Errorf("line %d: expected suffix %q, got %q", i, w, lines[i+2])
+		}
+	}
+}
+
+func TestCLI_UncompleteCommand(t *testing.T) {
+	for _, cmd := range []string{"uncomplete", "reopen"} {
+		c, out, _ := newTestCLI(&mockRepo{})
+
+		args := []string{"cli", cmd, "1", "2"}
+		c.Run(context.Background(), args)
+
+		got := out.String()
+		if !strings.Contains(got, "2 of 2 tasks successfully reopened") {
+			t.Errorf("%s: unexpected output: %q", cmd, got)
+		}
+	}
+}
+
+func TestCLI_InvalidIDs(t *testing.T) {
+	c, _, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "remove", "notanumber"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, "failed to parse ID") {
+		t.Errorf("expected parse error, got %q", got)
+	}
+}
+
+func TestCLI_NewCommandError(t *testing.T) {
+	c, _, errOut := newTestCLI(&errorRepo{})
+
+	args := []string{"cli", "new", "My Task"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, "create failed") {
+		t.Errorf("expected error printed, got %q", got)
+	}
+}
+
+func TestCLI_RemoveCommandError(t *testing.T) {
+	c, _, errOut := newTestCLI(&errorRepo{})
+
+	args := []string{"cli", "remove", "1"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, "delete failed") {
+		t.Errorf("expected error printed, got %q", got)
+	}
+}
+
+func TestCLI_ListCommandError(t *testing.T) {
+	c, _, errOut := newTestCLI(&errorRepo{})
+
+	args := []string{"cli", "list"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, "list failed") {
+		t.Errorf("expected error printed, got %q", got)
+	}
+}
+
+// completeErrorRepo only fails to complete the tasks
+type completeErrorRepo struct {
+	errorRepo
+}
+
+func (m *completeErrorRepo) Get(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error) {
+	return []task.Task{{ID: 1, Description: "Task 1"}}, nil
+}
+
+func TestCLI_CompleteCommandError(t *testing.T) {
+	c, _, errOut := newTestCLI(&completeErrorRepo{})
+
+	args := []string{"cli", "complete", "1"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, "complete failed") {
+		t.Errorf("expected error printed, got %q", got)
+	}
+}
+
+func TestCLI_EditCommand(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "edit", "1", "Task", "renamed"}
+	c.Run(context.Background(), args)
+
+	got := out.String()
+	if !strings.Contains(got, "Task renamed") {
+		t.Errorf("expected edited task printed, got %q", got)
+	}
+}
+
+func TestCLI_EditCommandNotFound(t *testing.T) {
+	c, _, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "edit", "99", "Nothing"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, "task not found") {
+		t.Errorf("expected not found error, got %q", got)
+	}
+}
+
+func TestCLI_ShowCommand(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "show", "2"}
+	c.Run(context.Background(), args)
+
+	got := out.String()
+	for _, want := range []string{"Task 2", "completed", "high", "Completed At:"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in task detail, got %q", want, got)
+		}
+	}
+}
+
+func TestCLI_AnnotateCommandMissingText(t *testing.T) {
+	c, _, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "annotate", "1"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, "Usage: cli-todo annotate <id> <text...>") {
+		t.Errorf("unexpected error output: %q", got)
+	}
+}
+
+func TestCLI_NewCommandEditor(t *testing.T) {
+	c, out, errOut := newTestCLI(&mockRepo{})
+	var contents []string
+	c.edit = func(path string) error {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		contents = append(contents, string(b))
+		doc := "Description: Fix the sink\nPriority: urgent\n"
+		if len(contents) > 1 {
+			doc = "Description: Fix the sink\nPriority: high\n\nCall the plumber\n"
+		}
+		return os.WriteFile(path, []byte(doc), 0o600)
+	}
+
+	args := []string{"cli", "new", "--edit", "Fix"}
+	c.Run(context.Background(), args)
+
+	if len(contents) != 2 {
+		t.Fatalf("expected the editor reopened once, got %d runs (errors: %q)", len(contents), errOut.String())
+	}
+	if !strings.Contains(contents[0], "Description: Fix\n") {
+		t.Errorf("expected the description in the first document, got %q", contents[0])
+	}
+	if !strings.HasPrefix(contents[1], "# ERROR: ") || !strings.Contains(contents[1], "invalid priority") {
+		t.Errorf("expected the error at the top of the document, got %q", contents[1])
+	}
+	if !strings.Contains(out.String(), "Fix the sink") {
+		t.Errorf("expected created task printed, got %q", out.String())
+	}
+}
+
+func TestCLI_NewCommandEditorAborted(t *testing.T) {
+	c, out, errOut := newTestCLI(&mockRepo{})
+	c.edit = func(path string) error {
+		return os.WriteFile(path, []byte("# nothing\n\n"), 0o600)
+	}
+
+	args := []string{"cli", "new", "--edit"}
+	c.Run(context.Background(), args)
+
+	if !strings.Contains(errOut.String(), "aborting") || out.Len() != 0 {
+		t.Errorf("expected edit aborted, got %q and %q", out.String(), errOut.String())
+	}
+}
+
+func TestCLI_NewCommandEditorUnchanged(t *testing.T) {
+	c, out, errOut := newTestCLI(&mockRepo{})
+	runs := 0
+	c.edit = func(path string) error {
+		runs++
+		return nil
+	}
+
+	code := c.Run(context.Background(), []string{"cli", "new", "--edit", "Buy milk"})
+
+	if runs != 1 || code != ExitOK || !containsRow(out.String(), "0 · 01/01/0001 00:00 Buy milk") {
+		t.Errorf("expected the task created as written, got %d runs, code %d, %q and %q", runs, code, out.String(), errOut.String())
+	}
+
+	// editing a task without changes aborts
+	c, out, errOut = newTestCLI(&mockRepo{})
+	runs = 0
+	c.edit = func(path string) error {
+		runs++
+		return nil
+	}
+	code = c.Run(context.Background(), []string{"cli", "edit", "--editor", "1"})
+
+	if runs != 1 || code != ExitFailure || !strings.Contains(errOut.String(), "unchanged") || out.Len() != 0 {
+		t.Errorf("expected edit aborted after one run, got %d runs, code %d, %q and %q", runs, code, out.String(), errOut.String())
+	}
+
+	// quitting when the editor is reopened with an error aborts too
+	c, out, errOut = newTestCLI(&mockRepo{})
+	runs = 0
+	c.edit = func(path string) error {
+		if runs++; runs == 1 {
+			return os.WriteFile(path, []byte("Description:\n"), 0o600)
+		}
+		return nil
+	}
+
+	code = c.Run(context.Background(), []string{"cli", "new", "--edit"})
+
+	if runs != 2 || code != ExitFailure || !strings.Contains(errOut.String(), "unchanged") || out.Len() != 0 {
+		t.Errorf("expected edit aborted after two runs, got %d runs, code %d, %q and %q", runs, code, out.String(), errOut.String())
+	}
+}
+
+func TestParseDocument(t *testing.T) {
+	due := time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local)
+	want := document{
+		description: "Write report",
+		priority:    task.Medium,
+		due:         &due,
+		tags:        []string{"work", "docs"},
+		project:     "Home",
+		repeat:      "weekly",
+		notes:       "first line\n\nsecond paragraph",
+	}
+
+	got, err := parseDocument(want.String())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.description != want.description || got.priority != want.priority ||
+		!got.due.Equal(due) || strings.Join(got.tags, " ") != "+work +docs" ||
+		got.project != want.project || got.repeat != want.repeat || got.notes != want.notes {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	_, err = parseDocument("Description: x\nColor: red\n")
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected unknown header error at line 2, got %v", err)
+	}
+}
+
+func TestCLI_OutputJSON(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "list", "all", "-o", "json"}
+	c.Run(context.Background(), args)
+
+	var got []map[string]any
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("expected a JSON array, got %q: %v", out.String(), err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("expected 2 tasks, got %v", got)
+	}
+	if v, ok := got[0]["completed_at"]; !ok || v != nil {
+		t.Errorf("expected explicit null completed_at, got %v", got[0])
+	}
+	completed, _ := got[1]["completed_at"].(string)
+	if _, err := time.Parse(time.RFC3339, completed); err != nil {
+		t.Errorf("expected RFC3339 completed_at, got %q", completed)
+	}
+	if got[1]["status"] != "completed" || got[1]["priority"] != "high" {
+		t.Errorf("unexpected task: %v", got[1])
+	}
+}
+
+func TestCLI_OutputResult(t *testing.T) {
+	tests := map[string]string{
+		"json": `"affected": 2`,
+		"yaml": "action: \"deleted\"",
+		"csv":  "action,items,task,affected,total\ndeleted,tasks,,2,2\n",
+		"tsv":  "deleted\ttasks\t\t2\t2\n",
+	}
+	for output, want := range tests {
+		c, out, _ := newTestCLI(&mockRepo{})
+
+		args := []string{"cli", "--output=" + output, "remove", "1", "2"}
+		c.Run(context.Background(), args)
+
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("%s: expected %q, got %q", output, want, out.String())
+		}
+	}
+}
+
+func TestTSV_Escaping(t *testing.T) {
+	tasks := []task.Task{{ID: 1, Description: `Say "hi"`, Notes: "first\tline\nsecond \\ line"}}
+
+	out := &bytes.Buffer{}
+	formatters["tsv"].Tasks(out, tasks)
+
+	rows := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(rows) != 2 {
+		t.Fatalf("expected a header and a row, got %q", out.String())
+	}
+	if !strings.Contains(rows[1], "\t"+`Say "hi"`+"\t") || !strings.Contains(rows[1], "\t"+`first\tline\nsecond \\ line`+"\t") {
+		t.Errorf("expected the fields escaped, got %q", rows[1])
+	}
+	if got, want := strings.Count(rows[1], "\t"), strings.Count(rows[0], "\t"); got != want {
+		t.Errorf("expected %d tabs like the header, got %d in %q", want, got, rows[1])
+	}
+}
+
+func TestCLI_OutputUnknown(t *testing.T) {
+	c, out, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "list", "-o", "xml"}
+	c.Run(context.Background(), args)
+
+	if !strings.Contains(errOut.String(), "unknown output format") || out.Len() != 0 {
+		t.Errorf("expected unknown format error, got %q and %q", out.String(), errOut.String())
+	}
+}
+
+func TestWriteYAML(t *testing.T) {
+	type inner struct {
+		Text string `json:"text"`
+	}
+	type record struct {
+		ID    int      `json:"id"`
+		Note  *string  `json:"note"`
+		Tags  []string `json:"tags"`
+		Items []inner  `json:"items"`
+	}
+	out := &bytes.Buffer{}
+	writeYAML(out, []record{{ID: 1, Tags: []string{"a", "b"}, Items: []inner{{"x"}}}, {ID: 2}})
+
+	want := `- id: 1
+  note: null
+  tags:
+    - "a"
+    - "b"
+  items:
+    - text: "x"
+- id: 2
+  note: null
+  tags: []
+  items: []
+`
+	if out.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, out.String())
+	}
+}
+
+func TestCLI_ListCommandFormat(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "list", "all", "--format", "{{.ID}} {{.Description}} {{if .CompletedAt}}done{{end}}"}
+	c.Run(context.Background(), args)
+
+	want := "1 Task 1 \n2 Task 2 done\n"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
+
+func TestCLI_ListCommandFormatExecError(t *testing.T) {
+	c, out, errOut := newTestCLI(&mockRepo{})
+
+	code := c.Run(context.Background(), []string{"cli", "list", "all", "--format", "{{.ID}} {{.Nope}}"})
+
+	if code != ExitUsage || out.Len() != 0 || !strings.Contains(errOut.String(), "can't evaluate field Nope") {
+		t.Errorf("expected a usage error and no output, got %d %q %q", code, out.String(), errOut.String())
+	}
+}
+
+func TestCLI_ListCommandNamedFormat(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+	c.config.Templates = map[string]string{"short": "{{pad 3 (printf \"%d\" .ID)}}|{{truncate 4 .Description}}"}
+
+	args := []string{"cli", "list", "--format", "short"}
+	c.Run(context.Background(), args)
+
+	want := "1  |Tas…\n2  |Tas…\n"
+	if out.String() != want {
+		t.Errorf("expected %q, got %q", want, out.String())
+	}
+}
+
+func TestCLI_ListCommandFormatWithOutput(t *testing.T) {
+	c, _, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "list", "--format", "{{.ID}}", "-o", "json"}
+	c.Run(context.Background(), args)
+
+	if !strings.Contains(errOut.String(), "--format cannot be used with --output") {
+		t.Errorf("unexpected error output: %q", errOut.String())
+	}
+}
+
+func TestRelative(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	tests := map[time.Duration]string{
+		30 * time.Second:     "now",
+		-5 * time.Minute:     "5 minutes ago",
+		time.Hour:            "in 1 hour",
+		3 * 24 * time.Hour:   "in 3 days",
+		-14 * 24 * time.Hour: "2 weeks ago",
+		400 * 24 * time.Hour: "in 1 year",
+	}
+	for d, want := range tests {
+		if got := relative(now.Add(d), now); got != want {
+			t.Errorf("%v: expected %q, got %q", d, want, got)
+		}
+	}
+}
+
+func TestCLI_RestoreCommand(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "restore", "1", "2"}
+	c.Run(context.Background(), args)
+
+	got := out.String()
+	if !strings.Contains(got, "2 of 2 tasks successfully restored") {
+		t.Errorf("unexpected output: %q", got)
+	}
+}
+
+func TestCLI_PurgeInvalidAge(t *testing.T) {
+	c, _, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "purge", "--older-than", "soon"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, "invalid age") {
+		t.Errorf("expected age error, got %q", got)
+	}
+}
+
+func TestCLI_ProjectAddCommand(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "project", "add", "Work"}
+	c.Run(context.Background(), args)
+
+	got := out.String()
+	if !strings.Contains(got, "Work") {
+		t.Errorf("expected project printed, got %q", got)
+	}
+}
+
+func TestCLI_ProjectAddExisting(t *testing.T) {
+	c, _, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "project", "add", "Home"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, "project already exists") {
+		t.Errorf("expected exists error, got %q", got)
+	}
+}
+
+func TestCLI_ProjectAssignCommand(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "project", "assign", "Home", "1", "2"}
+	c.Run(context.Background(), args)
+
+	got := out.String()
+	if !strings.Contains(got, "2 of 2 tasks successfully assigned") {
+		t.Errorf("unexpected output: %q", got)
+	}
+}
+
+func TestCLI_NewCommandUnknownProject(t *testing.T) {
+	c, _, errOut := newTestCLI(&mockRepo{})
+
+	args := []string{"cli", "new", "--project", "Garden", "Mow"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, "project not found") {
+		t.Errorf("expected not found error, got %q", got)
+	}
+}
+
+func TestCLI_ProjectListCommandError(t *testing.T) {
+	c, _, errOut := newTestCLI(&errorRepo{})
+
+	args := []string{"cli", "project", "list"}
+	c.Run(context.Background(), args)
+
+	got := errOut.String()
+	if !strings.Contains(got, "get projects failed") {
+		t.Errorf("expected error printed, got %q", got)
+	}
+}
+
+func TestManageListArgs_DefaultFilter(t *testing.T) {
+	_, filter, _, err := manageListArgs(nil, task.Ready)
+	if err != nil || filter != task.Ready {
+		t.Errorf("expected the ready filter, got %q (%v)", filter, err)
+	}
+	if _, _, _, err := manageListArgs([]string{"someday"}, task.Ready); exitCode(err) != ExitUsage {
+		t.Errorf("expected a usage error, got %v", err)
+	}
+}
+
+func TestCLI_ListCommandExpression(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+
+	code := c.Run(context.Background(), []string{"cli", "list", "status:completed", "priority>=high"})
+
+	if code != ExitOK || !strings.Contains(out.String(), "Task 2") || strings.Contains(out.String(), "Task 1") {
+		t.Errorf("expected only the completed task, got %d %q", code, out.String())
+	}
+
+	c, _, errOut := newTestCLI(&mockRepo{})
+	code = c.Run(context.Background(), []string{"cli", "list", "status:open and due<"})
+
+	want := "invalid filter at column 21: expected a value after <, found end of the filter\n" +
+		"  status:open and due<\n" +
+		"                      ^\n"
+	if code != ExitUsage || errOut.String() != want {
+		t.Errorf("expected the error pointing at the end, got %d %q", code, errOut.String())
+	}
+}
+
+func TestCLI_ListCommandDateFormat(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+	c.config.DateFormat = "2006-01-02"
+
+	c.Run(context.Background(), []string{"cli", "list"})
+
+	if !containsRow(out.String(), "1 · 0001-01-01 Task 1") {
+		t.Errorf("expected the dates as 2006-01-02, got %q", out.String())
+	}
+}
+
+func TestWriteTasks_Color(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	tasks := []task.Task{
+		{ID: 1, Description: "Late", DueAt: &past},
+		{ID: 2, Description: "On time"},
+	}
+
+	out := &bytes.Buffer{}
+	tableFormatter{dateFormat: config.DefaultDateFormat, color: true}.printTasks(out, tasks)
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasPrefix(lines[2], colors["red"]) || !strings.HasSuffix(lines[2], "\033[0m") {
+		t.Errorf("expected the overdue task in red, got %q", lines[2])
+	}
+	if strings.Contains(lines[3], "\033[") {
+		t.Errorf("expected the other task without colors, got %q", lines[3])
+	}
+
+	out.Reset()
+	tableFormatter{dateFormat: config.DefaultDateFormat}.printTasks(out, tasks)
+	if strings.Contains(out.String(), "\033[") {
+		t.Errorf("expected no colors, got %q", out.String())
+	}
+}
+
+func TestCLI_ListCommandColor(t *testing.T) {
+	c, out, _ := newTestCLI(&mockRepo{})
+	c.config = config.Default()
+
+	c.Run(context.Background(), []string{"cli", "list", "all"})
+	if strings.Contains(out.String(), "\033[") {
+		t.Errorf("expected no colors when the output is not a terminal, got %q", out.String())
+	}
+
+	out.Reset()
+	if err := c.config.Set("color", "true"); err != nil {
+		t.Fatal(err)
+	}
+	c.Run(context.Background(), []string{"cli", "list", "all"})
+	if !strings.Contains(out.String(), colors["gray"]) {
+		t.Errorf("expected the colors set in the config, got %q", out.String())
+	}
+}
+
+func TestCLI_SearchCommand(t *testing.T) {
+	c, out, errOut := newTestCLI(&mockRepo{})
+
+	if code := c.Run(context.Background(), []string{"cli", "search", "task", "NOT", "2"}); code != ExitOK {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errOut.String())
+	}
+	if got := out.String(); got != "1  ·  Task 1\n" {
+		t.Errorf("expected the matching task, got %q", got)
+	}
+
+	out.Reset()
+	c.Run(context.Background(), []string{"cli", "search", "-o", "json", "task*"})
+	if got := out.String(); !strings.Contains(got, `"id": 1`) || !strings.Contains(got, `"id": 2`) {
+		t.Errorf("expected the tasks in JSON, got %q", got)
+	}
+
+	if code := c.Run(context.Background(), []string{"cli", "search", "task", "OR"}); code != ExitValidation {
+		t.Errorf("expected exit code %d for an invalid search, got %d", ExitValidation, code)
+	}
+	if code := c.Run(context.Background(), []string{"cli", "search", "--filter", "nope", "task"}); code != ExitUsage {
+		t.Errorf("expected exit code %d for an invalid filter, got %d", ExitUsage, code)
+	}
+}
+
+func TestPrintMatches(t *testing.T) {
+	query, _ := task.ParseSearch("milk")
+	tasks := []task.Task{
+		{ID: 9, Description: "Buy milk"},
+		{ID: 12, Description: "Call the milkman", Notes: "ask him\nabout the Milk delivery"},
+	}
+
+	out := &bytes.Buffer{}
+	tableFormatter{color: true}.printMatches(out, tasks, query)
+	want := " 9  ·  Buy " + colors["yellow"] + "milk\033[0m\n" +
+		"12  ·  Call the milkman\n" +
+		"       about the " + colors["yellow"] + "Milk\033[0m delivery\n"
+	if got := out.String(); got != want {
+		t.Errorf("expected the highlighted matches\n%q\ngot\n%q", want, got)
+	}
+}
+
+func TestCLI_ConfigCommands(t *testing.T) {
+	c, out, errOut := newTestCLI(&mockRepo{})
+	c.config.Path = filepath.Join(t.TempDir(), "config")
+
+	if code := c.Run(context.Background(), []string{"cli", "config", "set", "date.format", "2006-01-02"}); code != ExitOK {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errOut.String())
+	}
+	if code := c.Run(context.Background(), []string{"cli", "config", "set", "color", "maybe"}); code != ExitValidation {
+		t.Errorf("expected exit code %d, got %d", ExitValidation, code)
+	}
+
+	cfg, err := config.Load(c.config.Path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.config = cfg
+	c.Run(context.Background(), []string{"cli", "config", "get", "date.format"})
+	if out.String() != "2006-01-02\n" {
+		t.Errorf("unexpected value %q", out.String())
+	}
+
+	out.Reset()
+	c.Run(context.Background(), []string{"cli", "config", "list"})
+	if !containsRow(out.String(), "list.filter uncompleted") {
+		t.Errorf("expected every setting listed, got %q", out.String())
+	}
+}
+
+func TestCLI_ConfigEdit(t *testing.T) {
+	c, _, errOut := newTestCLI(&mockRepo{})
+	c.config.Path = filepath.Join(t.TempDir(), "cli-todo", "config")
+	var seeded string
+	c.edit = func(path string) error {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		seeded = string(b)
+		return os.WriteFile(path, append(b, "color = maybe\n"...), 0o600)
+	}
+
+	code := c.Run(context.Background(), []string{"cli", "config", "edit"})
+	if code != ExitValidation {
+		t.Errorf("expected exit code %d, got %d", ExitValidation, code)
+	}
+	if !strings.Contains(seeded, `# date.format = "02/01/2006 15:04"`) {
+		t.Errorf("expected the new file to document the settings, got %q", seeded)
+	}
+	if !strings.Contains(errOut.String(), "config edit' to fix it") {
+		t.Errorf("expected the config error, got %q", errOut.String())
+	}
+}
+
+func TestExpandAliases(t *testing.T) {
+	aliases := map[string]string{
+		"ls":    "list all",
+		"t":     "today",
+		"today": `list today --format '{{.ID}} {{.Description}}'`,
+		"list":  "list completed",
+		"a":     "b",
+		"b":     "a",
+	}
+	builtin := func(name string) bool { return name == "list" }
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"cli", "ls", "--tree"}, []string{"cli", "list", "all", "--tree"}},
+		{[]string{"cli", "t"}, []string{"cli", "list", "today", "--format", "{{.ID}} {{.Description}}"}},
+		{[]string{"cli", "list"}, []string{"cli", "list"}},
+		{[]string{"cli", "show", "ls"}, []string{"cli", "show", "ls"}},
+	}
+	for _, tt := range tests {
+		got, err := expandAliases(tt.args, aliases, builtin)
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("%v: expected %q, got %q (%v)", tt.args, tt.want, got, err)
+		}
+	}
+
+	_, err := expandAliases([]string{"cli", "a"}, aliases, builtin)
+	if !errors.Is(err, errInvalidAlias) || !strings.Contains(err.Error(), "a -> b -> a") {
+		t.Errorf("expected an alias loop error, got %v", err)
+	}
+}
+
+func TestJoinWords(t *testing.T) {
+	words := []string{"list", "--format", "{{.ID}} {{.Description}}", "it's", ""}
+	got, err := splitWords(joinWords(words))
+	if err != nil || !slices.Equal(got, words) {
+		t.Errorf("expected %q, got %q (%v)", words, got, err)
+	}
+}
+
+func TestCLI_Alias(t *testing.T) {
+	c, out, errOut := newTestCLI(&mockRepo{})
+	c.config.Aliases = map[string]string{"ls": "list -o json"}
+
+	if code := c.Run(context.Background(), []string{"cli", "ls"}); code != ExitOK {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errOut.String())
+	}
+	if !strings.HasPrefix(out.String(), "[") {
+		t.Errorf("expected the JSON list, got %q", out.String())
+	}
+
+	out.Reset()
+	c.Run(context.Background(), []string{"cli", "-o", "csv", "ls"})
+	if !strings.HasPrefix(out.String(), "id,") {
+		t.Errorf("expected the flag of the user to win, got %q", out.String())
+	}
+}
+
+func TestCLI_AliasCommand(t *testing.T) {
+	c, out, errOut := newTestCLI(&mockRepo{})
+	c.config.Path = filepath.Join(t.TempDir(), "config")
+	run := func(args ...string) int {
+		return c.Run(context.Background(), append([]string{"cli", "alias"}, args...))
+	}
+
+	if code := run("add", "today", "list", "today", "--sort", "priority"); code != ExitOK {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errOut.String())
+	}
+	if code := run("add", "list", "list", "all"); code != ExitValidation {
+		t.Errorf("expected a command name to be rejected, got %d", code)
+	}
+	if code := run("add", "t", "t"); code != ExitValidation {
+		t.Errorf("expected a loop to be rejected, got %d", code)
+	}
+
+	cfg, err := config.Load(c.config.Path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c.config = cfg
+	run("list")
+	if !containsRow(out.String(), "today list today --sort priority") {
+		t.Errorf("expected the alias listed, got %q", out.String())
+	}
+
+	if code := run("remove", "today"); code != ExitOK {
+		t.Fatalf("expected exit code 0, got %d: %s", code, errOut.String())
+	}
+	if code := run("remove", "today"); code != ExitValidation {
+		t.Errorf("expected a missing alias to fail, got %d", code)
+	}
+}
+
+// filterRepo keeps the filter of the last listing
+type filterRepo struct {
+	mockRepo
+	filter task.ListFilter
+}
+
+func (m *filterRepo) Get(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error) {
+	m.filter = filter
+	return m.mockRepo.Get(ctx, ids, filter, opts)
+}
+
+func TestCLI_Complete(t *testing.T) {
+	repo := &filterRepo{}
+	c, out, _ := newTestCLI(repo)
+	c.config.Aliases = map[string]string{"rm": "remove", "ls": "list all"}
+
+	tests := []struct {
+		words  []string
+		want   string
+		filter task.ListFilter
+	}{
+		{[]string{"com"}, "complete\tComplete tasks\ncompletion\tPrint the completion script of bash, zsh or fish\n", ""},
+		{[]string{"r"}, "remove\tMove tasks to the trash, with their subtasks\nrestore\tBring removed tasks back from the trash, with their subtasks\nrecurrence\tManage the recurrence of a task\nrm\talias for 'remove'\n", ""},
+		{[]string{"complete", ""}, "1\tTask 1\n2\tTask 2\n", task.Uncompleted},
+		{[]string{"restore", "1", ""}, "2\tTask 2\n", task.Removed},
+		{[]string{"uncomplete", "--", ""}, "1\tTask 1\n2\tTask 2\n", task.Completed},
+		{[]string{"rm", "2", ""}, "1\tTask 1\n", task.Uncompleted},
+		{[]string{"list", "comp"}, "completed\tfilter\n", task.All},
+		{[]string{"list", "--so"}, "--sort\tsort the tasks by id, created, completed, deleted, due or priority\n", ""},
+		{[]string{"list", "--sort", ""}, "", ""},
+		{[]string{"-o", "j"}, "json\toutput format\n", ""},
+		{[]string{"prioritize", "1", "h"}, "high\n", ""},
+		{[]string{"project", "ren"}, "rename\tRename a project\n", ""},
+		{[]string{"ls", ""}, "1\tTask 1\n2\tTask 2\n", task.All},
+		{[]string{"--db", "other.db", "complete", ""}, "1\tTask 1\n2\tTask 2\n", task.Uncompleted},
+		{[]string{"__comp"}, "", ""},
+	}
+	for _, tt := range tests {
+		out.Reset()
+		repo.filter = ""
+		c.Run(context.Background(), append([]string{"cli", "__complete", "--"}, tt.words...))
+		if out.String() != tt.want {
+			t.Errorf("%q: expected %q, got %q", tt.words, tt.want, out.String())
+		}
+		if repo.filter != tt.filter {
+			t.Errorf("%q: expected the %q tasks, got %q", tt.words, tt.filter, repo.filter)
+		}
+	}
+}
+
+func TestCLI_CompletionCommand(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		c, out, _ := newTestCLI(&mockRepo{})
+		if code := c.Run(context.Background(), []string{"cli", "completion", shell}); code != ExitOK {
+			t.Errorf("%s: expected exit code 0, got %d", shell, code)
+		}
+		if !strings.Contains(out.String(), "__complete --") {
+			t.Errorf("%s: expected the script, got %q", shell, out.String())
+		}
+	}
+
+	c, out, _ := newTestCLI(&mockRepo{})
+	if code := c.Run(context.Background(), []string{"cli", "completion", "tcsh"}); code != ExitUsage {
+		t.Errorf("expected exit code %d, got %d", ExitUsage, code)
+	}
+	c.Run(context.Background(), []string{"cli", "help"})
+	if strings.Contains(out.String(), "__complete") {
+		t.Errorf("expected __complete to be hidden, got %q", out.String())
 	}
 }
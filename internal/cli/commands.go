@@ -0,0 +1,293 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"arcedo/cli-todo/internal/db"
+	"arcedo/cli-todo/internal/task"
+)
+
+const program = "cli-todo"
+
+// runner runs a command with its positional arguments,
+// once its flags have been parsed
+type runner func(ctx context.Context, args []string) error
+
+// command is a subcommand of the CLI, the help is generated from it
+type command struct {
+	name    string
+	aliases []string
+	// args is the spec of the positional arguments, e.g. "<id> [text...]".
+	// The arguments between <> are required and the ones between [] are
+	// optional, while "..." allows many. The number of arguments given is
+	// checked against it before running the command
+	args string
+	// summary is the one line description listed by help
+	summary string
+	// help is the long description shown by help <command>, if any
+	help string
+	// setup declares the flags of the command on fs
+	// and returns the function that runs it
+	setup func(fs *flag.FlagSet) runner
+	// subcommands are dispatched by their name when
+	// the command has no setup of its own
+	subcommands []*command
+	// rawArgs stops parsing the flags at the first positional argument,
+	// for the commands whose arguments are another command
+	rawArgs bool
+	// hidden commands are not listed by help nor suggested
+	hidden bool
+	// ids is the filter of the tasks offered to complete
+	// the ID arguments, the uncompleted ones when empty
+	ids task.ListFilter
+}
+
+// noFlags is the setup of the commands without flags
+func noFlags(run runner) func(fs *flag.FlagSet) runner {
+	return func(fs *flag.FlagSet) runner {
+		return run
+	}
+}
+
+func (cmd *command) is(name string) bool {
+	return cmd.name == name || slices.Contains(cmd.aliases, name)
+}
+
+// arity returns the number of positional arguments
+// the command accepts, max is -1 when unlimited
+func (cmd *command) arity() (min, max int) {
+	for _, arg := range strings.Fields(cmd.args) {
+		if strings.HasSuffix(strings.TrimRight(arg, ">]"), "...") {
+			max = -1
+		} else if max >= 0 {
+			max++
+		}
+		if strings.HasPrefix(arg, "<") {
+			min++
+		}
+	}
+	return min, max
+}
+
+func findCommand(cmds []*command, name string) *command {
+	for _, cmd := range cmds {
+		if cmd.is(name) {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// dispatch runs the command of cmds named by args[0], path is
+// the name of the parent command, if any, used in the messages
+func (c *CLI) dispatch(ctx context.Context, cmds []*command, path string, args []string) error {
+	cmd := findCommand(cmds, args[0])
+	if cmd == nil {
+		return unknownCommand(cmds, strings.TrimSpace(path+" "+args[0]))
+	}
+	path = strings.TrimSpace(path + " " + cmd.name)
+
+	if cmd.setup == nil {
+		if len(args) < 2 {
+			c.printCommandHelp(c.errOut, cmd, path)
+			return &exitError{code: ExitUsage}
+		}
+		if args[1] == "-h" || args[1] == "-help" || args[1] == "--help" {
+			c.printCommandHelp(c.out, cmd, path)
+			return nil
+		}
+		return c.dispatch(ctx, cmd.subcommands, path, args[1:])
+	}
+
+	fs := newFlagSet(path, io.Discard)
+	run := cmd.setup(fs)
+	var positional []string
+	var err error
+	if cmd.rawArgs {
+		err = fs.Parse(args[1:])
+		positional = fs.Args()
+	} else {
+		positional, err = parseFlags(fs, args[1:])
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		c.printCommandHelp(c.out, cmd, path)
+		return nil
+	}
+	if err != nil {
+		return usageErrorf("%v\n%s", err, commandUsage(cmd, path))
+	}
+	if min, max := cmd.arity(); len(positional) < min || (max >= 0 && len(positional) > max) {
+		return usageErrorf("wrong number of arguments for %s\n%s", path, commandUsage(cmd, path))
+	}
+	return run(ctx, positional)
+}
+
+// unknownCommand is the error of a command not found in cmds,
+// suggesting the ones with a similar name
+func unknownCommand(cmds []*command, name string) error {
+	msg := fmt.Sprintf("unknown command %q", name)
+	fields := strings.Fields(name)
+	if s := suggest(fields[len(fields)-1], cmds); len(s) > 0 {
+		msg += fmt.Sprintf(", did you mean %s?", strings.Join(s, " or "))
+	}
+	return usageErrorf("%s\nRun '%s help' for usage.", msg, program)
+}
+
+// suggest returns the quoted names of the commands closest to name,
+// those that start with it or are at most 2 edits away
+func suggest(name string, cmds []*command) []string {
+	var names []string
+	best := 3
+	for _, cmd := range cmds {
+		if cmd.hidden {
+			continue
+		}
+		d := 3
+		for _, n := range append([]string{cmd.name}, cmd.aliases...) {
+			d = min(d, editDistance(name, n))
+			if strings.HasPrefix(n, name) {
+				d = min(d, 1)
+			}
+		}
+		if d < best {
+			names, best = nil, d
+		}
+		if d == best && d <= 2 {
+			names = append(names, `"`+cmd.name+`"`)
+		}
+	}
+	return names
+}
+
+// editDistance is the number of insertions, deletions, substitutions
+// and transpositions of adjacent letters that turn a into b
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
+
+// help prints the help of the command named by args, or the
+// general usage without args
+func (c *CLI) help(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		c.printUsage()
+		return nil
+	}
+	cmds, path := c.commands, ""
+	for i, name := range args {
+		cmd := findCommand(cmds, name)
+		if value, ok := c.config.Aliases[name]; cmd == nil && ok && i == 0 {
+			printf(c.out, "%s is an alias for '%s'\n", name, value)
+			return nil
+		}
+		if cmd == nil {
+			return unknownCommand(cmds, strings.TrimSpace(path+" "+name))
+		}
+		path = strings.TrimSpace(path + " " + cmd.name)
+		if len(cmd.subcommands) == 0 || i == len(args)-1 {
+			c.printCommandHelp(c.out, cmd, path)
+			return nil
+		}
+		cmds = cmd.subcommands
+	}
+	return nil
+}
+
+func (c *CLI) printUsage() {
+	printf(c.out, "Usage: %s <command> [flags] [arguments]\n\n", program)
+	println(c.out, "Commands:")
+	printCommands(c.out, c.commands)
+	println(c.out, "\nGlobal flags:")
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	println(w, "  -o, --output\toutput format: table, json, yaml, csv or tsv")
+	println(w, "  --db\tpath of the database, or $"+db.EnvPath)
+	w.Flush()
+	printf(c.out, "\nRun '%s help <command>' for more information on a command.\n", program)
+}
+
+func printCommands(out io.Writer, cmds []*command) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, cmd := range cmds {
+		if !cmd.hidden {
+			printf(w, "  %s\t%s\n", cmd.name, cmd.summary)
+		}
+	}
+	w.Flush()
+}
+
+func usageLine(cmd *command, path string) string {
+	line := program + " " + path
+	if len(cmd.subcommands) > 0 {
+		line += " <command>"
+	}
+	if cmd.hasFlags() {
+		line += " [flags]"
+	}
+	if cmd.args != "" {
+		line += " " + cmd.args
+	}
+	return line
+}
+
+func commandUsage(cmd *command, path string) string {
+	return fmt.Sprintf("Usage: %s\nRun '%s help %s' for more information.", usageLine(cmd, path), program, path)
+}
+
+func (c *CLI) printCommandHelp(out io.Writer, cmd *command, path string) {
+	printf(out, "Usage: %s\n\n%s\n", usageLine(cmd, path), cmd.summary)
+	if cmd.help != "" {
+		printf(out, "\n%s\n", cmd.help)
+	}
+	if len(cmd.aliases) > 0 {
+		printf(out, "\nAliases: %s\n", strings.Join(cmd.aliases, ", "))
+	}
+	if len(cmd.subcommands) > 0 {
+		println(out, "\nCommands:")
+		printCommands(out, cmd.subcommands)
+	}
+	if cmd.hasFlags() {
+		println(out, "\nFlags:")
+		fs := newFlagSet(path, out)
+		cmd.setup(fs)
+		fs.PrintDefaults()
+	}
+}
+
+func (cmd *command) hasFlags() (found bool) {
+	if cmd.setup == nil {
+		return false
+	}
+	fs := newFlagSet(cmd.name, io.Discard)
+	cmd.setup(fs)
+	fs.VisitAll(func(*flag.Flag) {
+		found = true
+	})
+	return found
+}
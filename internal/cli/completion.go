@@ -0,0 +1,267 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"arcedo/cli-todo/internal/task"
+)
+
+// The completion scripts call the hidden __complete command with the
+// words of the command line after "--", the last one being the word to
+// complete. It prints a candidate per line, with its description after
+// a tab
+var completionScripts = map[string]string{
+	"bash": `# bash completion for cli-todo, add to ~/.bashrc:
+#   source <(cli-todo completion bash)
+_cli_todo() {
+    local IFS=$'\n'
+    COMPREPLY=($(cli-todo __complete -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null | cut -f1))
+}
+complete -o default -F _cli_todo cli-todo
+`,
+	"zsh": `#compdef cli-todo
+# zsh completion for cli-todo, add to ~/.zshrc:
+#   source <(cli-todo completion zsh)
+_cli_todo() {
+    local -a candidates
+    local line
+    for line in "${(@f)$(cli-todo __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)}"; do
+        [[ -n $line ]] && candidates+=("${line/$'\t'/:}")
+    done
+    _describe 'cli-todo' candidates
+}
+compdef _cli_todo cli-todo
+`,
+	"fish": `# fish completion for cli-todo, add to ~/.config/fish/config.fish:
+#   cli-todo completion fish | source
+function __cli_todo_complete
+    set -l words (commandline -opc) (commandline -ct)
+    cli-todo __complete -- $words[2..-1] 2>/dev/null
+end
+complete -c cli-todo -f -a '(__cli_todo_complete)'
+`,
+}
+
+func (c *CLI) completionCommands() []*command {
+	return []*command{
+		{
+			name:    "completion",
+			args:    "<shell>",
+			summary: "Print the completion script of bash, zsh or fish",
+			help:    "Load it in the shell with 'source <(cli-todo completion bash)', or zsh,\nor with 'cli-todo completion fish | source'.",
+			setup: noFlags(func(ctx context.Context, args []string) error {
+				script, ok := completionScripts[args[0]]
+				if !ok {
+					return usageErrorf("unknown shell %q, must be bash, zsh or fish", args[0])
+				}
+				printf(c.out, "%s", script)
+				return nil
+			}),
+		},
+		{
+			name:    "__complete",
+			args:    "[words...]",
+			summary: "Print the completions of the last word, used by the completion scripts",
+			hidden:  true,
+			rawArgs: true,
+			setup: noFlags(func(ctx context.Context, args []string) error {
+				for _, cand := range c.complete(ctx, args) {
+					if cand.description == "" {
+						println(c.out, cand.value)
+						continue
+					}
+					printf(c.out, "%s\t%s\n", cand.value, cand.description)
+				}
+				return nil
+			}),
+		},
+	}
+}
+
+// candidate is a completion of the word being typed
+type candidate struct {
+	value       string
+	description string
+}
+
+// complete returns the candidates for the last of words, which are the
+// words of the command line after the program name
+func (c *CLI) complete(ctx context.Context, words []string) []candidate {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current, words := words[len(words)-1], words[:len(words)-1]
+	if n := len(words); n > 0 && (words[n-1] == "-o" || words[n-1] == "-output" || words[n-1] == "--output") {
+		return matching(current, outputCandidates())
+	}
+	words, _, err := globalFlags(append([]string{program}, words...))
+	if err != nil {
+		return nil
+	}
+	if words, err = expandAliases(words, c.config.Aliases, c.builtin); err != nil {
+		return nil
+	}
+	words = words[1:]
+
+	cmds := c.commands
+	var cmd *command
+	for cmd == nil || cmd.setup == nil {
+		if len(words) == 0 {
+			cands := commandCandidates(cmds)
+			if cmd == nil {
+				cands = append(cands, c.aliasCandidates()...)
+			}
+			return matching(current, cands)
+		}
+		if cmd = findCommand(cmds, words[0]); cmd == nil {
+			return nil
+		}
+		cmds, words = cmd.subcommands, words[1:]
+	}
+
+	fs := newFlagSet(cmd.name, io.Discard)
+	cmd.setup(fs)
+	positional, flagValue, dashes := positionalArgs(fs, cmd, words)
+	switch {
+	case flagValue:
+		return nil
+	case strings.HasPrefix(current, "-") && !dashes:
+		var cands []candidate
+		fs.VisitAll(func(f *flag.Flag) {
+			cands = append(cands, candidate{"--" + f.Name, f.Usage})
+		})
+		return matching(current, cands)
+	}
+	return matching(current, c.argCandidates(ctx, cmd, positional))
+}
+
+// positionalArgs returns the positional arguments of words, reporting
+// whether the next word is the value of a flag or comes after "--"
+func positionalArgs(fs *flag.FlagSet, cmd *command, words []string) (positional []string, flagValue, dashes bool) {
+	for i := 0; i < len(words); i++ {
+		word := words[i]
+		if dashes || cmd.rawArgs && len(positional) > 0 || !strings.HasPrefix(word, "-") || word == "-" {
+			positional = append(positional, word)
+			continue
+		}
+		if word == "--" {
+			dashes = true
+			continue
+		}
+		name, _, hasValue := strings.Cut(strings.TrimLeft(word, "-"), "=")
+		if f := fs.Lookup(name); f != nil && !hasValue && !isBoolFlag(f) {
+			if i == len(words)-1 {
+				return positional, true, dashes
+			}
+			i++
+		}
+	}
+	return positional, false, dashes
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// argCandidates completes the next positional argument of cmd after
+// the given ones, by the name of the argument in cmd.args
+func (c *CLI) argCandidates(ctx context.Context, cmd *command, positional []string) []candidate {
+	specs := strings.Fields(cmd.args)
+	if len(specs) == 0 {
+		return nil
+	}
+	spec := specs[len(specs)-1]
+	if len(positional) < len(specs) {
+		spec = specs[len(positional)]
+	} else if !strings.Contains(spec, "...") {
+		return nil
+	}
+
+	switch name := strings.Trim(spec, "<>[]."); {
+	case strings.Contains(name, "id"):
+		cands := c.idCandidates(ctx, cmd.ids, positional)
+		if strings.Contains(name, "filter") && len(positional) == 0 {
+			for _, f := range task.ListFilters {
+				cands = append(cands, candidate{string(f), "filter"})
+			}
+		}
+		return cands
+	case name == "priority":
+		return []candidate{{"none", ""}, {"low", ""}, {"medium", ""}, {"high", ""}}
+	case name == "key":
+		var cands []candidate
+		for _, s := range c.config.Settings() {
+			cands = append(cands, candidate{s.Key, s.Help})
+		}
+		return cands
+	case name == "shell":
+		return []candidate{{"bash", ""}, {"zsh", ""}, {"fish", ""}}
+	case name == "command":
+		return commandCandidates(c.commands)
+	}
+	return nil
+}
+
+// idCandidates returns the IDs of the tasks of filter, the uncompleted
+// ones when empty, but the IDs already given
+func (c *CLI) idCandidates(ctx context.Context, filter task.ListFilter, given []string) []candidate {
+	if filter == "" {
+		filter = task.Uncompleted
+	}
+	tasks, err := c.taskService.List(ctx, nil, filter, task.ListOptions{})
+	if err != nil {
+		return nil
+	}
+	var cands []candidate
+	for _, t := range tasks {
+		id := strconv.Itoa(int(t.ID))
+		if !slices.Contains(given, id) {
+			cands = append(cands, candidate{id, t.Description})
+		}
+	}
+	return cands
+}
+
+func commandCandidates(cmds []*command) []candidate {
+	var cands []candidate
+	for _, cmd := range cmds {
+		if !cmd.hidden {
+			cands = append(cands, candidate{cmd.name, cmd.summary})
+		}
+	}
+	return cands
+}
+
+func (c *CLI) aliasCandidates() []candidate {
+	var cands []candidate
+	for name, value := range c.config.Aliases {
+		if !c.builtin(name) {
+			cands = append(cands, candidate{name, fmt.Sprintf("alias for '%s'", value)})
+		}
+	}
+	slices.SortFunc(cands, func(a, b candidate) int { return strings.Compare(a.value, b.value) })
+	return cands
+}
+
+func outputCandidates() []candidate {
+	var cands []candidate
+	for name := range formatters {
+		cands = append(cands, candidate{name, "output format"})
+	}
+	slices.SortFunc(cands, func(a, b candidate) int { return strings.Compare(a.value, b.value) })
+	return cands
+}
+
+// matching keeps the candidates that start with prefix
+func matching(prefix string, cands []candidate) []candidate {
+	return slices.DeleteFunc(cands, func(cand candidate) bool {
+		return !strings.HasPrefix(cand.value, prefix)
+	})
+}
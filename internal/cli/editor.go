@@ -0,0 +1,241 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"arcedo/cli-todo/internal/config"
+	"arcedo/cli-todo/internal/task"
+)
+
+// The task document written to the editor has one "Header: value" per
+// line, then a blank line and the notes. The lines starting with '#'
+// before the notes are comments, and an empty document aborts
+const documentHelp = `# Write the task below. The notes go after the first blank line.
+# Lines starting with '#' before the notes are ignored and an empty file aborts.
+`
+
+var (
+	errEditAborted   = errors.New("empty task, aborting")
+	errEditUnchanged = errors.New("task unchanged, aborting")
+	errDocument      = errors.New("invalid task document")
+)
+
+// document holds the editable fields of a task as written by the user
+type document struct {
+	description string
+	priority    task.Priority
+	due         *time.Time
+	tags        []string
+	project     string
+	repeat      string
+	notes       string
+}
+
+func newDocument(t task.Task) document {
+	d := document{
+		description: t.Description,
+		priority:    t.Priority,
+		due:         t.DueAt,
+		repeat:      t.Recurrence,
+		notes:       t.Notes,
+	}
+	for _, tag := range t.Tags {
+		d.tags = append(d.tags, tag.Name)
+	}
+	if t.Project != nil {
+		d.project = t.Project.Name
+	}
+	return d
+}
+
+func (d document) String() string {
+	var b strings.Builder
+	b.WriteString(documentHelp)
+	printf(&b, "Description: %s\n", d.description)
+	printf(&b, "Priority: %s\n", d.priority)
+	due := ""
+	if d.due != nil {
+		due = d.due.Format(config.DefaultDateFormat)
+	}
+	printf(&b, "Due: %s\n", due)
+	tags := make([]string, len(d.tags))
+	for i, tag := range d.tags {
+		tags[i] = "+" + tag
+	}
+	printf(&b, "Tags: %s\n", strings.Join(tags, " "))
+	printf(&b, "Project: %s\n", d.project)
+	printf(&b, "Repeat: %s\n", d.repeat)
+	printf(&b, "\n%s", d.notes)
+	if d.notes != "" && !strings.HasSuffix(d.notes, "\n") {
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// parseDocument reads back a document written by String
+func parseDocument(s string) (d document, err error) {
+	if strings.TrimSpace(stripComments(s)) == "" {
+		return document{}, errEditAborted
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(s))
+	var notes []string
+	line, inNotes := 0, false
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if inNotes {
+			notes = append(notes, text)
+			continue
+		}
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+		if strings.TrimSpace(text) == "" {
+			inNotes = true
+			continue
+		}
+
+		name, value, ok := strings.Cut(text, ":")
+		if !ok {
+			return document{}, fmt.Errorf("%w: line %d: expected 'Header: value', got %q", errDocument, line, text)
+		}
+		value = strings.TrimSpace(value)
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "description":
+			d.description = value
+		case "priority":
+			if d.priority, err = task.ParsePriority(value); err != nil {
+				return document{}, fmt.Errorf("%w: line %d: %w", errDocument, line, err)
+			}
+		case "due":
+			if d.due, err = parseDue(value); err != nil {
+				return document{}, fmt.Errorf("%w: line %d: %w", errDocument, line, err)
+			}
+		case "tags":
+			d.tags = strings.Fields(value)
+		case "project":
+			d.project = value
+		case "repeat":
+			d.repeat = value
+		default:
+			return document{}, fmt.Errorf("%w: line %d: unknown header %q", errDocument, line, name)
+		}
+	}
+	d.notes = strings.TrimSpace(strings.Join(notes, "\n"))
+	return d, nil
+}
+
+func stripComments(s string) string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// retryEdit reports whether the user can fix err by editing the document
+func retryEdit(err error) bool {
+	for _, target := range []error{
+		errDocument,
+		task.ErrEmptyDescription,
+		task.ErrInvalidPriority,
+		task.ErrInvalidRecurrence,
+		task.ErrInvalidTag,
+		task.ErrProjectNotFound,
+		task.ErrProjectArchived,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// editDocument opens d in the editor until save accepts the result.
+// While the result cannot be parsed or validated the editor is opened
+// again with the error as a comment at the top. Like git, it aborts
+// when the document is saved as it was written, the error aside, if
+// abortUnchanged or once the editor was opened again
+func (c *CLI) editDocument(d document, abortUnchanged bool, save func(d document) error) error {
+	f, err := os.CreateTemp("", "cli-todo-*.txt")
+	if err != nil {
+		return &exitError{ExitFailure, fmt.Errorf("failed to create the task file: %w", err)}
+	}
+	path := f.Name()
+	defer os.Remove(path)
+	_ = f.Close()
+
+	content := d.String()
+	for {
+		written := stripErrorComment(content)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			return &exitError{ExitFailure, fmt.Errorf("failed to write the task file: %w", err)}
+		}
+		if err := c.edit(path); err != nil {
+			return &exitError{ExitFailure, fmt.Errorf("failed to run the editor: %w", err)}
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return &exitError{ExitFailure, fmt.Errorf("failed to read the task file: %w", err)}
+		}
+		content = string(b)
+		if abortUnchanged && stripErrorComment(content) == written {
+			return &exitError{ExitFailure, errEditUnchanged}
+		}
+
+		d, err := parseDocument(content)
+		if err == nil {
+			err = save(d)
+		}
+		if errors.Is(err, errEditAborted) {
+			return &exitError{ExitFailure, err}
+		}
+		if err == nil || !retryEdit(err) {
+			return err
+		}
+		content = errorComment(err) + stripErrorComment(content)
+		abortUnchanged = true
+	}
+}
+
+const errorPrefix = "# ERROR: "
+
+func errorComment(err error) string {
+	var b strings.Builder
+	for _, line := range strings.Split(err.Error(), "\n") {
+		b.WriteString(errorPrefix + line + "\n")
+	}
+	return b.String()
+}
+
+// stripErrorComment removes the error left by a previous attempt
+func stripErrorComment(s string) string {
+	for strings.HasPrefix(s, errorPrefix) {
+		_, s, _ = strings.Cut(s, "\n")
+	}
+	return s
+}
+
+// runEditor opens the file with $VISUAL or $EDITOR, vi by default
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	args := strings.Fields(editor)
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,124 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"arcedo/cli-todo/internal/config"
+	"arcedo/cli-todo/internal/task"
+)
+
+// The exit codes returned by Run
+const (
+	ExitOK = iota
+	// ExitFailure is any other failure, e.g. the editor could not run
+	ExitFailure
+	// ExitUsage is an unknown command or wrong flags or arguments
+	ExitUsage
+	// ExitValidation is an invalid value, e.g. an empty description
+	ExitValidation
+	// ExitNotFound is a task or project that does not exist
+	ExitNotFound
+	// ExitStorage is a failure of the database
+	ExitStorage
+	// ExitPartial is an action that only succeeded for some of the
+	// tasks, e.g. "1 of 3 tasks successfully deleted"
+	ExitPartial
+)
+
+// exitError ends Run with its code, printing err if it is not nil
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit code %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+func usageErrorf(format string, a ...any) error {
+	return &exitError{ExitUsage, fmt.Errorf(format, a...)}
+}
+
+var (
+	validationErrors = []error{
+		task.ErrEmptyDescription,
+		task.ErrInvalidPriority,
+		task.ErrInvalidDate,
+		task.ErrInvalidTag,
+		task.ErrInvalidRecurrence,
+		task.ErrInvalidParent,
+		task.ErrEmptyAnnotation,
+		task.ErrEmptyProjectName,
+		task.ErrProjectExists,
+		task.ErrProjectArchived,
+		task.ErrOpenSubtasks,
+		task.ErrBlocked,
+		task.ErrInvalidSearch,
+		config.ErrInvalidConfig,
+		config.ErrUnknownKey,
+		errInvalidAlias,
+	}
+	notFoundErrors = []error{
+		task.ErrTaskNotFound,
+		task.ErrTaskRemoved,
+		task.ErrProjectNotFound,
+	}
+)
+
+// exitCode classifies err. The failures of the storage are reported
+// by the service, any other error is a general failure
+func exitCode(err error) int {
+	var exit *exitError
+	var cycle *task.DependencyCycleError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &exit):
+		return exit.code
+	case errors.As(err, &cycle) || isAny(err, validationErrors):
+		return ExitValidation
+	case isAny(err, notFoundErrors):
+		return ExitNotFound
+	case errors.Is(err, task.ErrStorage):
+		return ExitStorage
+	}
+	return ExitFailure
+}
+
+func isAny(err error, targets []error) bool {
+	for _, target := range targets {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// result prints r and returns the error of its exit code when the
+// action did not succeed for every task of ids, ExitNotFound when
+// none of them exist
+func (c *CLI) result(ctx context.Context, r result, ids []int) error {
+	c.format.Result(c.out, r)
+	if r.Affected >= r.Total {
+		return nil
+	}
+	if r.Affected == 0 && len(ids) > 0 {
+		found, err := c.taskService.List(ctx, ids, task.IDs, task.ListOptions{})
+		if err != nil {
+			return err
+		}
+		if len(found) == 0 {
+			return &exitError{code: ExitNotFound}
+		}
+	}
+	return &exitError{code: ExitPartial}
+}
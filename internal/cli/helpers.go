@@ -1,22 +1,85 @@
 package cli
 
 import (
+	"flag"
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
+	"time"
 )
 
+func newFlagSet(name string, errOut io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(errOut)
+	return fs
+}
+
+// parseFlags parses the flags of fs allowing them to be mixed with
+// the positional arguments, which are returned in the same order.
+// The arguments after "--" are all positional
+func parseFlags(fs *flag.FlagSet, args []string) (positional []string, err error) {
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := fs.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		args = rest
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// stringsFlag is a flag that can be repeated, collecting every value
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringsFlag) Set(s string) error {
+	*f = append(*f, s)
+	return nil
+}
+
 func validateIDs(ss []string) (ids []int, err error) {
 	for _, s := range ss {
 		id, err := strconv.Atoi(s)
 		if err != nil {
-			return nil, fmt.Errorf("failed to parse ID %v: %w", s, err)
+			return nil, usageErrorf("failed to parse ID %v: %w", s, err)
 		}
 		ids = append(ids, id)
 	}
 	return ids, nil
 }
 
+// parseAge works like time.ParseDuration but also accepts
+// days and weeks, e.g. "30d" or "2w". An empty string is 0
+func parseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	units := map[byte]time.Duration{'d': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	if unit, ok := units[s[len(s)-1]]; ok {
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil || n < 0 {
+			return 0, usageErrorf("invalid age %q", s)
+		}
+		return time.Duration(n) * unit, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, usageErrorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
 func println(out io.Writer, a ...any) {
 	_, _ = fmt.Fprintln(out, a...)
 }
@@ -0,0 +1,451 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+
+	"arcedo/cli-todo/internal/config"
+	"arcedo/cli-todo/internal/task"
+)
+
+// formatter writes the output of the commands in one format.
+// The errors go to the error output as plain text regardless of it
+type formatter interface {
+	Tasks(out io.Writer, tasks []task.Task)
+	// Task writes the full detail of a single task
+	Task(out io.Writer, t task.Task)
+	Tags(out io.Writer, tags []task.TagCount)
+	Projects(out io.Writer, projects []task.Project)
+	Result(out io.Writer, r result)
+}
+
+// result is the outcome of an action applied to several items,
+// e.g. "2 of 3 tasks successfully deleted"
+type result struct {
+	// Action is the past tense of the action, e.g. "deleted"
+	Action   string
+	Affected int
+	Total    int
+	// Items is what the action was applied to, "tasks" when empty
+	Items string
+	// Task is the task the items were related to, if any,
+	// e.g. "task 2 successfully blocked by 1 tasks"
+	Task int
+}
+
+const defaultOutput = "table"
+
+// formatters holds every output format by its name
+var formatters = map[string]formatter{
+	defaultOutput: tableFormatter{},
+	"json":        jsonFormatter{},
+	"yaml":        yamlFormatter{},
+	"csv":         csvFormatter{comma: ','},
+	"tsv":         csvFormatter{comma: '\t'},
+}
+
+func parseOutput(s string) (formatter, error) {
+	if s == "" {
+		s = defaultOutput
+	}
+	f, ok := formatters[s]
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q, must be table, json, yaml, csv or tsv", s)
+	}
+	return f, nil
+}
+
+// tableFormatter writes aligned tables for humans
+type tableFormatter struct {
+	// dateFormat is the layout of the dates
+	dateFormat string
+	// color enables the colors of paint
+	color bool
+}
+
+// newTableFormatter writes to out, colored by default only
+// when it is a terminal and NO_COLOR is not set
+func newTableFormatter(cfg config.Config, out io.Writer) tableFormatter {
+	color := cfg.Color
+	if !cfg.ColorSet {
+		f, ok := out.(*os.File)
+		color = color && ok && term.IsTerminal(int(f.Fd())) && os.Getenv("NO_COLOR") == ""
+	}
+	return tableFormatter{dateFormat: cfg.DateFormat, color: color}
+}
+
+func (f tableFormatter) Tasks(out io.Writer, tasks []task.Task) { f.printTasks(out, tasks) }
+
+func (f tableFormatter) Task(out io.Writer, t task.Task) { f.printTask(out, t) }
+
+func (tableFormatter) Tags(out io.Writer, tags []task.TagCount) { printTags(out, tags) }
+
+func (f tableFormatter) Projects(out io.Writer, projects []task.Project) {
+	f.printProjects(out, projects)
+}
+
+var colors = map[string]string{
+	"bold":    "\033[1m",
+	"red":     "\033[31m",
+	"green":   "\033[32m",
+	"yellow":  "\033[33m",
+	"blue":    "\033[34m",
+	"magenta": "\033[35m",
+	"cyan":    "\033[36m",
+	"gray":    "\033[90m",
+}
+
+// paint colors s with one of colors, s is left
+// as it is when the colors are disabled
+func (f tableFormatter) paint(color, s string) string {
+	if !f.color {
+		return s
+	}
+	return colors[color] + s + "\033[0m"
+}
+
+func (tableFormatter) Result(out io.Writer, r result) {
+	items := r.items()
+	if r.Task != 0 {
+		printf(out, "task %v successfully %s by %v %s\n", r.Task, r.Action, r.Affected, items)
+		return
+	}
+	printf(out, "%v of %v %s successfully %s\n", r.Affected, r.Total, items, r.Action)
+}
+
+func (r result) items() string {
+	if r.Items == "" {
+		return "tasks"
+	}
+	return r.Items
+}
+
+// The records are the stable schema of the machine-readable
+// formats, documented in the README. The timestamps are RFC3339
+// and the missing values are null
+
+type taskRecord struct {
+	ID          uint               `json:"id"`
+	Description string             `json:"description"`
+	Status      string             `json:"status"`
+	Priority    string             `json:"priority"`
+	Project     *string            `json:"project"`
+	Context     *string            `json:"context"`
+	ParentID    *uint              `json:"parent_id"`
+	Tags        []string           `json:"tags"`
+	Recurrence  *string            `json:"recurrence"`
+	Notes       string             `json:"notes"`
+	DueAt       *string            `json:"due_at"`
+	CreatedAt   string             `json:"created_at"`
+	UpdatedAt   string             `json:"updated_at"`
+	CompletedAt *string            `json:"completed_at"`
+	DeletedAt   *string            `json:"deleted_at"`
+	Annotations []annotationRecord `json:"annotations"`
+}
+
+type annotationRecord struct {
+	CreatedAt string `json:"created_at"`
+	Text      string `json:"text"`
+}
+
+type tagRecord struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+type projectRecord struct {
+	ID         uint    `json:"id"`
+	Name       string  `json:"name"`
+	CreatedAt  string  `json:"created_at"`
+	ArchivedAt *string `json:"archived_at"`
+}
+
+type resultRecord struct {
+	Action   string `json:"action"`
+	Items    string `json:"items"`
+	Task     *int   `json:"task"`
+	Affected int    `json:"affected"`
+	Total    int    `json:"total"`
+}
+
+func newTaskRecord(t task.Task) taskRecord {
+	r := taskRecord{
+		ID:          t.ID,
+		Description: t.Description,
+		Status:      status(t),
+		Priority:    t.Priority.String(),
+		ParentID:    t.ParentID,
+		Tags:        []string{},
+		Notes:       t.Notes,
+		DueAt:       timestamp(t.DueAt),
+		CreatedAt:   t.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   t.UpdatedAt.Format(time.RFC3339),
+		CompletedAt: timestamp(t.CompletedAt),
+		DeletedAt:   timestamp(t.DeletedAt),
+		Annotations: []annotationRecord{},
+	}
+	if t.Project != nil {
+		r.Project = &t.Project.Name
+	}
+	if t.Context != "" {
+		r.Context = &t.Context
+	}
+	if t.Recurrence != "" {
+		r.Recurrence = &t.Recurrence
+	}
+	for _, tag := range t.Tags {
+		r.Tags = append(r.Tags, tag.Name)
+	}
+	for _, a := range t.Annotations {
+		r.Annotations = append(r.Annotations, annotationRecord{a.CreatedAt.Format(time.RFC3339), a.Text})
+	}
+	return r
+}
+
+func taskRecords(tasks []task.Task) []taskRecord {
+	records := make([]taskRecord, len(tasks))
+	for i, t := range tasks {
+		records[i] = newTaskRecord(t)
+	}
+	return records
+}
+
+func tagRecords(tags []task.TagCount) []tagRecord {
+	records := make([]tagRecord, len(tags))
+	for i, t := range tags {
+		records[i] = tagRecord{t.Name, t.Count}
+	}
+	return records
+}
+
+func projectRecords(projects []task.Project) []projectRecord {
+	records := make([]projectRecord, len(projects))
+	for i, p := range projects {
+		records[i] = projectRecord{p.ID, p.Name, p.CreatedAt.Format(time.RFC3339), timestamp(p.ArchivedAt)}
+	}
+	return records
+}
+
+func newResultRecord(r result) resultRecord {
+	record := resultRecord{Action: r.Action, Items: r.items(), Affected: r.Affected, Total: r.Total}
+	if r.Task != 0 {
+		record.Task = &r.Task
+	}
+	return record
+}
+
+func timestamp(t *time.Time) *string {
+	if t == nil {
+		return nil
+	}
+	s := t.Format(time.RFC3339)
+	return &s
+}
+
+// status is the state of the task as shown to the user
+func status(t task.Task) string {
+	switch {
+	case t.DeletedAt != nil:
+		return "removed"
+	case t.CompletedAt != nil:
+		return "completed"
+	}
+	return "open"
+}
+
+// jsonFormatter writes the lists as JSON arrays
+// and the single items as JSON objects
+type jsonFormatter struct{}
+
+func (jsonFormatter) Tasks(out io.Writer, tasks []task.Task) { writeJSON(out, taskRecords(tasks)) }
+
+func (jsonFormatter) Task(out io.Writer, t task.Task) { writeJSON(out, newTaskRecord(t)) }
+
+func (jsonFormatter) Tags(out io.Writer, tags []task.TagCount) { writeJSON(out, tagRecords(tags)) }
+
+func (jsonFormatter) Projects(out io.Writer, projects []task.Project) {
+	writeJSON(out, projectRecords(projects))
+}
+
+func (jsonFormatter) Result(out io.Writer, r result) { writeJSON(out, newResultRecord(r)) }
+
+func writeJSON(out io.Writer, v any) {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+// yamlFormatter writes the same records as jsonFormatter in YAML
+type yamlFormatter struct{}
+
+func (yamlFormatter) Tasks(out io.Writer, tasks []task.Task) { writeYAML(out, taskRecords(tasks)) }
+
+func (yamlFormatter) Task(out io.Writer, t task.Task) { writeYAML(out, newTaskRecord(t)) }
+
+func (yamlFormatter) Tags(out io.Writer, tags []task.TagCount) { writeYAML(out, tagRecords(tags)) }
+
+func (yamlFormatter) Projects(out io.Writer, projects []task.Project) {
+	writeYAML(out, projectRecords(projects))
+}
+
+func (yamlFormatter) Result(out io.Writer, r result) { writeYAML(out, newResultRecord(r)) }
+
+// writeYAML writes a record or a slice of records in block style,
+// using the json tags as keys. The strings are always double quoted
+func writeYAML(out io.Writer, v any) {
+	var b strings.Builder
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Slice && value.Len() == 0 {
+		b.WriteString("[]\n")
+	}
+	encodeYAML(&b, value, "")
+	_, _ = io.WriteString(out, b.String())
+}
+
+// encodeYAML writes v, whose first line is already indented
+func encodeYAML(b *strings.Builder, v reflect.Value, indent string) {
+	switch v.Kind() {
+	case reflect.Struct:
+		typ := v.Type()
+		for i := range typ.NumField() {
+			if i > 0 {
+				b.WriteString(indent)
+			}
+			key, _, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			b.WriteString(key + ":")
+
+			field := v.Field(i)
+			switch {
+			case field.Kind() != reflect.Slice:
+				b.WriteString(" " + yamlScalar(field) + "\n")
+			case field.Len() == 0:
+				b.WriteString(" []\n")
+			default:
+				b.WriteString("\n" + indent + "  ")
+				encodeYAML(b, field, indent+"  ")
+			}
+		}
+	case reflect.Slice:
+		for i := range v.Len() {
+			if i > 0 {
+				b.WriteString(indent)
+			}
+			b.WriteString("- ")
+			encodeYAML(b, v.Index(i), indent+"  ")
+		}
+	default:
+		b.WriteString(yamlScalar(v) + "\n")
+	}
+}
+
+func yamlScalar(v reflect.Value) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return "null"
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return strconv.Quote(v.String())
+	case reflect.Int, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// csvFormatter writes one header row and one row per item,
+// with the fields separated by comma. The lists of values,
+// like the tags, are separated by spaces. The TSV fields are
+// not quoted but escaped, a row per line, see tsvEscaper
+type csvFormatter struct {
+	comma rune
+}
+
+func (f csvFormatter) Tasks(out io.Writer, tasks []task.Task) {
+	rows := [][]string{{
+		"id", "description", "status", "priority", "project", "context", "parent_id", "tags", "recurrence",
+		"notes", "due_at", "created_at", "updated_at", "completed_at", "deleted_at",
+	}}
+	for _, r := range taskRecords(tasks) {
+		parent := ""
+		if r.ParentID != nil {
+			parent = strconv.FormatUint(uint64(*r.ParentID), 10)
+		}
+		rows = append(rows, []string{
+			strconv.FormatUint(uint64(r.ID), 10), r.Description, r.Status, r.Priority,
+			value(r.Project), value(r.Context), parent, strings.Join(r.Tags, " "), value(r.Recurrence),
+			r.Notes, value(r.DueAt), r.CreatedAt, r.UpdatedAt, value(r.CompletedAt), value(r.DeletedAt),
+		})
+	}
+	f.write(out, rows)
+}
+
+func (f csvFormatter) Task(out io.Writer, t task.Task) { f.Tasks(out, []task.Task{t}) }
+
+func (f csvFormatter) Tags(out io.Writer, tags []task.TagCount) {
+	rows := [][]string{{"name", "count"}}
+	for _, r := range tagRecords(tags) {
+		rows = append(rows, []string{r.Name, strconv.Itoa(r.Count)})
+	}
+	f.write(out, rows)
+}
+
+func (f csvFormatter) Projects(out io.Writer, projects []task.Project) {
+	rows := [][]string{{"id", "name", "created_at", "archived_at"}}
+	for _, r := range projectRecords(projects) {
+		rows = append(rows, []string{strconv.FormatUint(uint64(r.ID), 10), r.Name, r.CreatedAt, value(r.ArchivedAt)})
+	}
+	f.write(out, rows)
+}
+
+func (f csvFormatter) Result(out io.Writer, r result) {
+	record := newResultRecord(r)
+	id := ""
+	if record.Task != nil {
+		id = strconv.Itoa(*record.Task)
+	}
+	f.write(out, [][]string{
+		{"action", "items", "task", "affected", "total"},
+		{record.Action, record.Items, id, strconv.Itoa(record.Affected), strconv.Itoa(record.Total)},
+	})
+}
+
+// tsvEscaper escapes the tabs, line breaks and backslashes of
+// the TSV fields, as the TSV of e.g. PostgreSQL and MySQL do
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func (f csvFormatter) write(out io.Writer, rows [][]string) {
+	if f.comma == '\t' {
+		for _, row := range rows {
+			fields := make([]string, len(row))
+			for i, field := range row {
+				fields[i] = tsvEscaper.Replace(field)
+			}
+			println(out, strings.Join(fields, "\t"))
+		}
+		return
+	}
+	w := csv.NewWriter(out)
+	w.Comma = f.comma
+	_ = w.WriteAll(rows)
+}
+
+// value is the text of an optional value, empty when missing
+func value(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
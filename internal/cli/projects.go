@@ -0,0 +1,121 @@
+package cli
+
+import (
+	"context"
+	"io"
+	"text/tabwriter"
+
+	"arcedo/cli-todo/internal/task"
+)
+
+func (c *CLI) projectCommand() *command {
+	return &command{
+		name:    "project",
+		summary: "Manage the projects that group tasks",
+		subcommands: []*command{
+			{
+				name:    "add",
+				args:    "<name>",
+				summary: "Create a project",
+				setup: noFlags(func(ctx context.Context, args []string) error {
+					p, err := c.taskService.AddProject(ctx, args[0])
+					if err != nil {
+						return err
+					}
+					c.format.Projects(c.out, []task.Project{p})
+					return nil
+				}),
+			},
+			{
+				name:    "list",
+				summary: "List the projects",
+				setup: noFlags(func(ctx context.Context, args []string) error {
+					projects, err := c.taskService.Projects(ctx)
+					if err != nil {
+						return err
+					}
+					c.format.Projects(c.out, projects)
+					return nil
+				}),
+			},
+			{
+				name:    "rename",
+				args:    "<name> <new-name>",
+				summary: "Rename a project",
+				setup: noFlags(func(ctx context.Context, args []string) error {
+					p, err := c.taskService.RenameProject(ctx, args[0], args[1])
+					if err != nil {
+						return err
+					}
+					c.format.Projects(c.out, []task.Project{p})
+					return nil
+				}),
+			},
+			{
+				name:    "archive",
+				args:    "<name>",
+				summary: "Archive a project, hiding its tasks from the default listing",
+				setup: noFlags(func(ctx context.Context, args []string) error {
+					p, err := c.taskService.ArchiveProject(ctx, args[0])
+					if err != nil {
+						return err
+					}
+					c.format.Projects(c.out, []task.Project{p})
+					return nil
+				}),
+			},
+			{
+				name:    "assign",
+				args:    "<name> <ids...>",
+				summary: "Move tasks to a project",
+				setup: noFlags(func(ctx context.Context, args []string) error {
+					return c.assign(ctx, "assigned", args[0], args[1:])
+				}),
+			},
+			{
+				name:    "unassign",
+				args:    "<ids...>",
+				summary: "Remove tasks from their project",
+				setup: noFlags(func(ctx context.Context, args []string) error {
+					return c.assign(ctx, "unassigned", "", args)
+				}),
+			},
+		},
+	}
+}
+
+// assign moves the tasks to the project with that name, none when empty
+func (c *CLI) assign(ctx context.Context, action, name string, args []string) error {
+	ids, err := validateIDs(args)
+	if err != nil {
+		return err
+	}
+	affected, err := c.taskService.Assign(ctx, ids, name)
+	if err != nil {
+		return err
+	}
+	return c.result(ctx, result{Action: action, Affected: affected, Total: len(ids)}, ids)
+}
+
+func (f tableFormatter) printProjects(out io.Writer, projects []task.Project) {
+	if len(projects) == 0 {
+		println(out, "No projects found")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	println(w, "ID\tName\tCreated At\tArchived At")
+	println(w, "------------------------------------------------")
+
+	for _, p := range projects {
+		archived := ""
+		if p.ArchivedAt != nil {
+			archived = p.ArchivedAt.Format(f.dateFormat)
+		}
+
+		printf(w, "%d\t%s\t%s\t%s\n", p.ID, p.Name, p.CreatedAt.Format(f.dateFormat), archived)
+	}
+
+	w.Flush()
+}
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"arcedo/cli-todo/internal/config"
+)
+
+func (c *CLI) configCommand() *command {
+	return &command{
+		name:    "config",
+		summary: "Show and change the settings of the config file",
+		help:    "The aliases and templates are set with the aliases.<name> and templates.<name> keys.",
+		subcommands: []*command{
+			{
+				name:    "get",
+				args:    "<key>",
+				summary: "Show the value of a setting",
+				setup: noFlags(func(ctx context.Context, args []string) error {
+					value, err := c.config.Get(args[0])
+					if err != nil {
+						return err
+					}
+					println(c.out, value)
+					return nil
+				}),
+			},
+			{
+				name:    "set",
+				args:    "<key> <value...>",
+				summary: "Change a setting, keeping the rest of the file as it is",
+				setup: noFlags(func(ctx context.Context, args []string) error {
+					if c.config.Path == "" {
+						return &exitError{ExitFailure, errors.New("there is no config file")}
+					}
+					key, value := args[0], strings.Join(args[1:], " ")
+					if name, ok := strings.CutPrefix(key, "aliases."); ok {
+						if err := c.checkAlias(name, value); err != nil {
+							return err
+						}
+					}
+					return config.SetValue(c.config.Path, key, value)
+				}),
+			},
+			{
+				name:    "list",
+				summary: "List every setting with its value",
+				setup: noFlags(func(ctx context.Context, args []string) error {
+					w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+					for _, s := range c.config.Settings() {
+						printf(w, "%s\t%s\n", s.Key, s.Value)
+					}
+					w.Flush()
+					return nil
+				}),
+			},
+			{
+				name:    "edit",
+				summary: "Open the config file in $EDITOR",
+				setup:   noFlags(c.editConfig),
+			},
+		},
+	}
+}
+
+// editConfig opens the config file in the editor, creating it with the
+// help of every setting when missing, and checks it once saved
+func (c *CLI) editConfig(ctx context.Context, args []string) error {
+	path := c.config.Path
+	if path == "" {
+		return &exitError{ExitFailure, errors.New("there is no config file")}
+	}
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return &exitError{ExitFailure, fmt.Errorf("failed to create the config directory: %w", err)}
+		}
+		if err := os.WriteFile(path, []byte(configHelp(c.config)), 0o600); err != nil {
+			return &exitError{ExitFailure, fmt.Errorf("failed to create the config file: %w", err)}
+		}
+	}
+	if err := c.edit(path); err != nil {
+		return &exitError{ExitFailure, fmt.Errorf("failed to run the editor: %w", err)}
+	}
+	if _, err := config.Load(path); err != nil {
+		return fmt.Errorf("%w\nrun '%s config edit' to fix it", err, program)
+	}
+	return nil
+}
+
+// configHelp is the content of a new config file,
+// every setting commented out with its default
+func configHelp(cfg config.Config) string {
+	var b strings.Builder
+	printf(&b, "# %s config, the lines starting with '#' are comments\n", program)
+	for _, s := range cfg.Settings() {
+		if s.Help != "" {
+			printf(&b, "\n# %s\n# %s\n", s.Help, s.TOML())
+		}
+	}
+	printf(&b, "\n# [aliases]\n# t = \"list today\"\n")
+	printf(&b, "\n# [templates]\n# short = \"{{.ID}} {{.Description}}\"\n")
+	return b.String()
+}
@@ -2,116 +2,888 @@ package cli
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"io"
+	"slices"
+	"strings"
 	"text/tabwriter"
+	"time"
 
+	"arcedo/cli-todo/internal/query"
 	"arcedo/cli-todo/internal/task"
 )
 
-func (c *CLI) runTask(ctx context.Context, args []string) {
-	switch args[1] {
-	case "new":
-		tasks, err := c.taskService.Create(ctx, args[2:])
-		if err != nil {
-			println(c.errOut, err)
-			return
-		}
-		printTasks(c.out, tasks)
+func (c *CLI) taskCommands() []*command {
+	return []*command{
+		{
+			name:    "new",
+			args:    "[descriptions...]",
+			summary: "Create a task for each description",
+			help: "The descriptions are read for the fields of the tasks, unless --raw:\n" +
+				"  !high, !medium, !low    the priority\n" +
+				"  +tag                    a tag\n" +
+				"  @context                the context, e.g. @phone\n" +
+				"  #project                the project, which must exist\n" +
+				"  tomorrow 3pm, friday, next friday, in 3 days, eow, eom...\n" +
+				"                          the due date\n" +
+				"With --edit a single task is written in $VISUAL or $EDITOR.",
+			setup: func(fs *flag.FlagSet) runner {
+				priority := fs.String("priority", "none", "priority of the new tasks (none, low, medium, high)")
+				due := fs.String("due", "", "due date of the new tasks (YYYY-MM-DD [HH:MM], today, tomorrow)")
+				var tags stringsFlag
+				fs.Var(&tags, "tag", "tag of the new tasks, can be repeated")
+				project := fs.String("project", "", "name of the project of the new tasks")
+				parent := fs.Int("parent", 0, "ID of the task the new tasks are subtasks of")
+				every := fs.String("every", "", "repeat the tasks when completed (daily, weekly, every 3 days, mon,thu...)")
+				notes := fs.String("notes", "", "notes of the new tasks")
+				edit := fs.Bool("edit", false, "write a single task in $EDITOR, starting from the other flags")
+				raw := fs.Bool("raw", false, "keep the descriptions as written, without reading fields from them")
+				return func(ctx context.Context, descs []string) error {
+					if len(descs) == 0 && !*edit {
+						return usageErrorf("new needs at least one description")
+					}
+					p, err := task.ParsePriority(*priority)
+					if err != nil {
+						return err
+					}
+					dueAt, err := parseDue(*due)
+					if err != nil {
+						return err
+					}
+					opts := task.CreateOptions{
+						Priority:   p,
+						DueAt:      dueAt,
+						Tags:       tags,
+						Project:    *project,
+						Parent:     *parent,
+						Recurrence: *every,
+						Notes:      *notes,
+						Raw:        *raw,
+					}
+					if *edit {
+						return c.newInEditor(ctx, descs, opts)
+					}
+					tasks, err := c.taskService.Create(ctx, descs, opts)
+					if err != nil {
+						return err
+					}
+					c.format.Tasks(c.out, tasks)
+					return nil
+				}
+			},
+		},
+		{
+			name:    "remove",
+			args:    "<ids...>",
+			summary: "Move tasks to the trash, with their subtasks",
+			setup: noFlags(func(ctx context.Context, args []string) error {
+				ids, err := validateIDs(args)
+				if err != nil {
+					return err
+				}
+				affected, err := c.taskService.Delete(ctx, ids)
+				if err != nil {
+					return err
+				}
+				return c.result(ctx, result{Action: "deleted", Affected: affected, Total: len(ids)}, ids)
+			}),
+		},
+		{
+			name:    "list",
+			ids:     task.All,
+			args:    "[filter|expression|ids...]",
+			summary: "List tasks",
+			help: "The filter is one of all, uncompleted (the default), completed, removed,\n" +
+				"overdue, today, upcoming, blocked or ready. Otherwise the tasks with the given IDs are listed,\n" +
+				"or the ones matching a filter expression, e.g.\n" +
+				"  cli-todo list 'status:open and (tag:work or priority>=high) and due<+7d'\n" +
+				"The fields are status, id, priority, description (desc), notes, project, context,\n" +
+				"tag, due, created, updated and completed, compared with =, :, !=, <, <=, >, >=\n" +
+				"or ~ (contains). The dates are YYYY-MM-DD, today, +7d, -2w, +1m... and none\n" +
+				"matches a missing value. The expression only keeps the removed tasks with status:removed.",
+			setup: func(fs *flag.FlagSet) runner {
+				priorities := fs.String("priority", "", "comma separated priorities to keep (e.g. high,medium)")
+				sort := fs.String("sort", "", "sort the tasks by id, created, completed, deleted, due or priority")
+				desc := fs.Bool("desc", false, "reverse the sort order")
+				var tags stringsFlag
+				fs.Var(&tags, "tag", "keep the tasks with this tag, can be repeated")
+				allTags := fs.Bool("all-tags", false, "keep only the tasks with all the given tags instead of any")
+				project := fs.String("project", "", "keep only the tasks of this project")
+				tree := fs.Bool("tree", false, "show the subtasks indented below their parent")
+				format := fs.String("format", "", "render each task with a Go template, or a named template of the config")
+				return func(ctx context.Context, args []string) error {
+					if *format != "" {
+						if _, table := c.format.(tableFormatter); !table {
+							return usageErrorf("--format cannot be used with --output")
+						}
+						var err error
+						if c.format, err = newTemplateFormatter(*format, c.config, c.out, c.errOut); err != nil {
+							return err
+						}
+					}
+					IDs, filter, where, err := manageListArgs(args, c.config.ListFilter)
+					if err != nil {
+						return err
+					}
+					if *sort == "" {
+						*sort = string(c.config.ListSort)
+					}
+					opts, err := listOptions(*priorities, *sort, *desc)
+					if err != nil {
+						return err
+					}
+					opts.Tags, opts.AllTags, opts.Project = tags, *allTags, *project
+					opts.Where = where
+					tasks, err := c.taskService.List(ctx, IDs, filter, opts)
+					if err != nil {
+						return err
+					}
+					if tmpl, ok := c.format.(templateFormatter); ok {
+						return tmpl.write(c.out, tasks)
+					}
+					table, ok := c.format.(tableFormatter)
+					if !*tree || !ok {
+						c.format.Tasks(c.out, tasks)
+						return nil
+					}
+					progress, err := c.taskService.Progress(ctx, tasks)
+					if err != nil {
+						return err
+					}
+					table.printTree(c.out, tasks, progress)
+					return nil
+				}
+			},
+		},
+		{
+			name:    "search",
+			args:    "<query...>",
+			summary: "Search the descriptions and notes of the tasks, best matches first",
+			help: "The words match whole words, ignoring the case. A word ending with '*'\n" +
+				"matches the words starting with it and the words between double quotes\n" +
+				"match as a phrase. The words are joined with AND unless separated by OR,\n" +
+				"while 'a NOT b' excludes b. Parentheses group them, e.g.\n" +
+				"  cli-todo search '\"buy milk\" OR (bread NOT fresh*)'",
+			setup: func(fs *flag.FlagSet) runner {
+				filter := fs.String("filter", string(task.All), "search only the tasks of this filter (all, completed, overdue...)")
+				return func(ctx context.Context, args []string) error {
+					f := task.ListFilter(*filter)
+					if !slices.Contains(task.ListFilters, f) {
+						return usageErrorf("invalid filter %q", *filter)
+					}
+					query, err := task.ParseSearch(strings.Join(args, " "))
+					if err != nil {
+						return err
+					}
+					tasks, err := c.taskService.Search(ctx, query, f)
+					if err != nil {
+						return err
+					}
+					if table, ok := c.format.(tableFormatter); ok {
+						table.printMatches(c.out, tasks, query)
+						return nil
+					}
+					c.format.Tasks(c.out, tasks)
+					return nil
+				}
+			},
+		},
+		{
+			name:    "show",
+			ids:     task.All,
+			args:    "<id>",
+			summary: "Show every detail of a task, even if it is removed",
+			setup: noFlags(func(ctx context.Context, args []string) error {
+				ids, err := validateIDs(args)
+				if err != nil {
+					return err
+				}
+				t, err := c.taskService.Show(ctx, ids[0])
+				if err != nil {
+					return err
+				}
+				c.format.Task(c.out, t)
+				return nil
+			}),
+		},
+		{
+			name:    "complete",
+			ids:     task.Uncompleted,
+			args:    "<ids...>",
+			summary: "Complete tasks",
+			help: "The tasks with open subtasks or open blockers are skipped, unless\n" +
+				"--recursive or --force are given. Completing a recurrent task creates the next one.",
+			setup: func(fs *flag.FlagSet) runner {
+				recursive := fs.Bool("recursive", false, "complete the open subtasks too")
+				force := fs.Bool("force", false, "complete the tasks even if their blockers, or the ones of their subtasks, are still open")
+				return func(ctx context.Context, args []string) error {
+					ids, err := validateIDs(args)
+					if err != nil {
+						return err
+					}
+					affected, err := c.taskService.Complete(ctx, ids, task.CompleteOptions{
+						Recursive: *recursive,
+						Force:     *force,
+					})
+					if err == nil {
+						return c.result(ctx, result{Action: "completed", Affected: affected, Total: len(ids)}, ids)
+					}
+					subtasks, blocked := errors.Is(err, task.ErrOpenSubtasks), errors.Is(err, task.ErrBlocked)
+					if !subtasks && !blocked {
+						return err
+					}
+					// some tasks were skipped, the rest are completed anyway
+					c.format.Result(c.out, result{Action: "completed", Affected: affected, Total: len(ids)})
+					if subtasks {
+						err = fmt.Errorf("%w\nuse --recursive to complete the open subtasks too", err)
+					}
+					if blocked {
+						err = fmt.Errorf("%w\nuse --force to complete the blocked tasks anyway", err)
+					}
+					code := ExitPartial
+					if affected == 0 {
+						code = ExitValidation
+					}
+					return &exitError{code, err}
+				}
+			},
+		},
+		{
+			name:    "uncomplete",
+			ids:     task.Completed,
+			aliases: []string{"reopen"},
+			args:    "<ids...>",
+			summary: "Reopen completed tasks",
+			setup: noFlags(func(ctx context.Context, args []string) error {
+				ids, err := validateIDs(args)
+				if err != nil {
+					return err
+				}
+				affected, err := c.taskService.Uncomplete(ctx, ids)
+				if err != nil {
+					return err
+				}
+				return c.result(ctx, result{Action: "reopened", Affected: affected, Total: len(ids)}, ids)
+			}),
+		},
+		{
+			name:    "restore",
+			ids:     task.Removed,
+			args:    "<ids...>",
+			summary: "Bring removed tasks back from the trash, with their subtasks",
+			setup: noFlags(func(ctx context.Context, args []string) error {
+				ids, err := validateIDs(args)
+				if err != nil {
+					return err
+				}
+				affected, err := c.taskService.Restore(ctx, ids)
+				if err != nil {
+					return err
+				}
+				return c.result(ctx, result{Action: "restored", Affected: affected, Total: len(ids)}, ids)
+			}),
+		},
+		{
+			name:    "purge",
+			ids:     task.Removed,
+			args:    "[ids...]",
+			summary: "Delete removed tasks for good, all of them without IDs",
+			setup: func(fs *flag.FlagSet) runner {
+				olderThan := fs.String("older-than", "", "only purge tasks removed before this age (e.g. 30d, 12h)")
+				return func(ctx context.Context, args []string) error {
+					age, err := parseAge(*olderThan)
+					if err != nil {
+						return err
+					}
+					ids, err := validateIDs(args)
+					if err != nil {
+						return err
+					}
+					affected, total, err := c.taskService.Purge(ctx, ids, age)
+					if err != nil {
+						return err
+					}
+					return c.result(ctx, result{Action: "purged", Affected: affected, Total: total}, ids)
+				}
+			},
+		},
+		{
+			name:    "edit",
+			args:    "<id> [description...]",
+			summary: "Change the description of a task",
+			help:    "With --editor every field of the task is edited in $VISUAL or $EDITOR.",
+			setup: func(fs *flag.FlagSet) runner {
+				editor := fs.Bool("editor", false, "edit every field of the task in $EDITOR")
+				return func(ctx context.Context, args []string) error {
+					if !*editor && len(args) < 2 {
+						return usageErrorf("edit needs an ID and the new description")
+					}
+					ids, err := validateIDs(args[:1])
+					if err != nil {
+						return err
+					}
+					if *editor {
+						return c.editInEditor(ctx, ids[0])
+					}
+					t, err := c.taskService.Edit(ctx, ids[0], strings.Join(args[1:], " "))
+					if err != nil {
+						return err
+					}
+					c.format.Tasks(c.out, []task.Task{t})
+					return nil
+				}
+			},
+		},
+		{
+			name:    "prioritize",
+			args:    "<id> <priority>",
+			summary: "Set the priority of a task: none, low, medium or high",
+			setup: noFlags(func(ctx context.Context, args []string) error {
+				ids, err := validateIDs(args[:1])
+				if err != nil {
+					return err
+				}
+				p, err := task.ParsePriority(args[1])
+				if err != nil {
+					return err
+				}
+				t, err := c.taskService.Prioritize(ctx, ids[0], p)
+				if err != nil {
+					return err
+				}
+				c.format.Tasks(c.out, []task.Task{t})
+				return nil
+			}),
+		},
+		{
+			name:    "due",
+			args:    "<id> <date...>",
+			summary: "Set the due date of a task, none clears it",
+			setup: noFlags(func(ctx context.Context, args []string) error {
+				ids, err := validateIDs(args[:1])
+				if err != nil {
+					return err
+				}
+				dueAt, err := parseDue(strings.Join(args[1:], " "))
+				if err != nil {
+					return err
+				}
+				t, err := c.taskService.SetDue(ctx, ids[0], dueAt)
+				if err != nil {
+					return err
+				}
+				c.format.Tasks(c.out, []task.Task{t})
+				return nil
+			}),
+		},
+		{
+			name:    "tag",
+			args:    "<id> <tags...>",
+			summary: "Add tags to a task",
+			setup:   noFlags(c.changeTags(c.taskService.Tag)),
+		},
+		{
+			name:    "untag",
+			args:    "<id> <tags...>",
+			summary: "Remove tags from a task",
+			setup:   noFlags(c.changeTags(c.taskService.Untag)),
+		},
+		{
+			name:    "tags",
+			summary: "List the tags with their number of tasks",
+			setup: noFlags(func(ctx context.Context, args []string) error {
+				tags, err := c.taskService.Tags(ctx)
+				if err != nil {
+					return err
+				}
+				c.format.Tags(c.out, tags)
+				return nil
+			}),
+		},
+		{
+			name:    "move",
+			args:    "<id>",
+			summary: "Make a task a subtask of another one",
+			setup: func(fs *flag.FlagSet) runner {
+				parent := fs.Int("parent", 0, "ID of the new parent task, 0 moves it to the top level")
+				return func(ctx context.Context, args []string) error {
+					ids, err := validateIDs(args)
+					if err != nil {
+						return err
+					}
+					t, err := c.taskService.Move(ctx, ids[0], *parent)
+					if err != nil {
+						return err
+					}
+					c.format.Tasks(c.out, []task.Task{t})
+					return nil
+				}
+			},
+		},
+		{
+			name:    "block",
+			args:    "<id>",
+			summary: "Mark a task as blocked by other tasks",
+			setup: func(fs *flag.FlagSet) runner {
+				var by stringsFlag
+				fs.Var(&by, "by", "ID of the blocker task, can be repeated")
+				return func(ctx context.Context, args []string) error {
+					ids, err := blockerIDs("block", args, by)
+					if err != nil {
+						return err
+					}
+					if err := c.taskService.Block(ctx, ids[0], ids[1:]); err != nil {
+						return err
+					}
+					return c.result(ctx, result{Action: "blocked", Affected: len(ids[1:]), Total: len(ids[1:]), Task: ids[0]}, ids[1:])
+				}
+			},
+		},
+		{
+			name:    "unblock",
+			args:    "<id>",
+			summary: "Remove blockers of a task",
+			setup: func(fs *flag.FlagSet) runner {
+				var by stringsFlag
+				fs.Var(&by, "by", "ID of the blocker task, can be repeated")
+				return func(ctx context.Context, args []string) error {
+					ids, err := blockerIDs("unblock", args, by)
+					if err != nil {
+						return err
+					}
+					affected, err := c.taskService.Unblock(ctx, ids[0], ids[1:])
+					if err != nil {
+						return err
+					}
+					return c.result(ctx, result{Action: "removed", Affected: affected, Total: len(ids[1:]), Items: "blockers"}, ids[1:])
+				}
+			},
+		},
+		{
+			name:    "annotate",
+			args:    "<id> <text...>",
+			summary: "Add a timestamped annotation to a task",
+			setup:   noFlags(c.changeNotes(c.taskService.Annotate)),
+		},
+		{
+			name:    "notes",
+			args:    "<id> [text...]",
+			summary: "Replace the notes of a task, no text clears them",
+			setup:   noFlags(c.changeNotes(c.taskService.SetNotes)),
+		},
+		{
+			name:    "recurrence",
+			summary: "Manage the recurrence of a task",
+			subcommands: []*command{
+				{
+					name:    "stop",
+					args:    "<id>",
+					summary: "Stop repeating a task",
+					setup: noFlags(func(ctx context.Context, args []string) error {
+						ids, err := validateIDs(args)
+						if err != nil {
+							return err
+						}
+						t, err := c.taskService.StopRecurrence(ctx, ids[0])
+						if err != nil {
+							return err
+						}
+						c.format.Tasks(c.out, []task.Task{t})
+						return nil
+					}),
+				},
+			},
+		},
+	}
+}
 
-	case "remove":
-		ids, err := validateIDs(args[2:])
+// changeTags runs change with the ID and the tags of args
+func (c *CLI) changeTags(change func(ctx context.Context, id int, tags []string) (task.Task, error)) runner {
+	return func(ctx context.Context, args []string) error {
+		ids, err := validateIDs(args[:1])
 		if err != nil {
-			println(c.errOut, err)
-			return
+			return err
 		}
-		affected, err := c.taskService.Delete(ctx, ids)
+		t, err := change(ctx, ids[0], args[1:])
 		if err != nil {
-			println(c.errOut, err)
-			return
+			return err
 		}
-		printf(c.out, "%v of %v tasks successfully deleted\n", affected, len(ids))
+		c.format.Tasks(c.out, []task.Task{t})
+		return nil
+	}
+}
 
-	case "list":
-		IDs, filter, err := manageListArgs(args[2:])
+// changeNotes runs change with the ID and the text of args
+func (c *CLI) changeNotes(change func(ctx context.Context, id int, text string) (task.Task, error)) runner {
+	return func(ctx context.Context, args []string) error {
+		ids, err := validateIDs(args[:1])
 		if err != nil {
-			println(c.errOut, err)
-			c.printUsage()
-			return
+			return err
 		}
-		tasks, err := c.taskService.List(ctx, IDs, filter)
+		t, err := change(ctx, ids[0], strings.Join(args[1:], " "))
 		if err != nil {
-			println(c.errOut, err)
-			return
+			return err
 		}
-		printTasks(c.out, tasks)
+		c.format.Task(c.out, t)
+		return nil
+	}
+}
 
-	case "complete":
-		ids, err := validateIDs(args[2:])
-		if err != nil {
-			println(c.errOut, err)
-			return
+// blockerIDs returns the ID of the task followed by the IDs of the blockers
+func blockerIDs(name string, args []string, by []string) ([]int, error) {
+	if len(by) == 0 {
+		return nil, usageErrorf("%s needs at least one --by", name)
+	}
+	return validateIDs(append(args, by...))
+}
+
+// manageListArgs returns the IDs, the filter or the filter expression
+// given to list, the default filter without arguments
+func manageListArgs(args []string, defaultFilter task.ListFilter) ([]int, task.ListFilter, task.Condition, error) {
+	if len(args) == 0 {
+		return nil, defaultFilter, nil, nil
+	}
+	if IDs, err := validateIDs(args); err == nil {
+		return IDs, task.IDs, nil, nil
+	}
+	if filter := task.ListFilter(args[0]); len(args) == 1 && slices.Contains(task.ListFilters, filter) {
+		return nil, filter, nil, nil
+	}
+	q, err := query.Parse(strings.Join(args, " "))
+	if err != nil {
+		return nil, task.All, nil, &exitError{ExitUsage, err}
+	}
+	return nil, task.Matching, q, nil
+}
+
+func listOptions(priorities, sort string, desc bool) (opts task.ListOptions, err error) {
+	if priorities != "" {
+		for _, s := range strings.Split(priorities, ",") {
+			p, err := task.ParsePriority(s)
+			if err != nil {
+				return opts, err
+			}
+			opts.Priorities = append(opts.Priorities, p)
 		}
-		affected, err := c.taskService.Complete(ctx, ids)
-		if err != nil {
-			println(c.errOut, err)
-			return
+	}
+	opts.Desc = desc
+	if sort == "" {
+		return opts, nil
+	}
+	for _, o := range task.ListOrderValues {
+		if string(o) == sort {
+			opts.Order = o
+			return opts, nil
 		}
-		printf(c.out, "%v of %v tasks successfully completed\n", affected, len(ids))
+	}
+	return opts, usageErrorf("invalid sort field: %s", sort)
+}
+
+// newInEditor creates a single task written in the editor,
+// which starts with the description and options given. The fields
+// of the document are the task's, the description is kept as written
+func (c *CLI) newInEditor(ctx context.Context, descs []string, opts task.CreateOptions) error {
+	if len(descs) > 1 {
+		return usageErrorf("new --edit creates a single task")
+	}
+	opts.Raw = true
+	d := document{
+		description: strings.Join(descs, ""),
+		priority:    opts.Priority,
+		due:         opts.DueAt,
+		tags:        opts.Tags,
+		project:     opts.Project,
+		repeat:      opts.Recurrence,
+		notes:       opts.Notes,
+	}
+	var tasks []task.Task
+	err := c.editDocument(d, false, func(d document) (err error) {
+		opts.Priority, opts.DueAt, opts.Tags = d.priority, d.due, d.tags
+		opts.Project, opts.Recurrence, opts.Notes = d.project, d.repeat, d.notes
+		tasks, err = c.taskService.Create(ctx, []string{d.description}, opts)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	c.format.Tasks(c.out, tasks)
+	return nil
+}
 
-	default:
-		c.printUsage()
+// editInEditor changes every field of a task in the editor
+func (c *CLI) editInEditor(ctx context.Context, id int) error {
+	t, err := c.taskService.Show(ctx, id)
+	if err == nil && t.DeletedAt != nil {
+		err = fmt.Errorf("task %d: %w", id, task.ErrTaskRemoved)
+	}
+	if err != nil {
+		return err
+	}
+	err = c.editDocument(newDocument(t), true, func(d document) (err error) {
+		t, err = c.taskService.Change(ctx, id, task.Changes{
+			Description: d.description,
+			Notes:       d.notes,
+			Priority:    d.priority,
+			DueAt:       d.due,
+			Tags:        d.tags,
+			Project:     d.project,
+			Recurrence:  d.repeat,
+		})
+		return err
+	})
+	if err != nil {
+		return err
 	}
+	c.format.Task(c.out, t)
+	return nil
 }
 
-func manageListArgs(args []string) ([]int, task.ListFilter, error) {
-	if len(args) == 0 {
-		return nil, task.Uncompleted, nil // default list uncompleted
+// parseDue parses the due date given by the user,
+// an empty string or none means no due date
+func parseDue(s string) (*time.Time, error) {
+	if s == "" || s == "none" {
+		return nil, nil
 	}
-	IDs, err := validateIDs(args)
+	due, err := task.ParseDate(s, time.Now())
 	if err != nil {
-		switch args[0] {
-		case "all":
-			return nil, task.All, nil
-		case "completed":
-			return nil, task.Completed, nil
-		case "uncompleted":
-			return nil, task.Uncompleted, nil
-		case "removed":
-			return nil, task.Removed, nil
-		default:
-			return nil, task.All, fmt.Errorf("invalid arguments: %s", args[0])
+		return nil, err
+	}
+	return &due, nil
+}
+
+func (f tableFormatter) printTasks(out io.Writer, tasks []task.Task) {
+	f.writeTasks(out, tasks, func(t task.Task) string {
+		return t.Description
+	})
+}
+
+// printMatches lists the tasks found by a search with the matched
+// words highlighted, followed by the lines of the notes that match
+func (f tableFormatter) printMatches(out io.Writer, tasks []task.Task, query task.SearchQuery) {
+	if len(tasks) == 0 {
+		println(out, "No tasks found")
+		return
+	}
+	width := 0
+	for _, t := range tasks {
+		width = max(width, len(fmt.Sprint(t.ID)))
+	}
+	mark := func(s string) string { return f.paint("yellow", s) }
+	for _, t := range tasks {
+		status := "·"
+		if t.CompletedAt != nil {
+			status = "✓"
+		}
+		printf(out, "%*d  %s  %s\n", width, t.ID, status, query.Highlight(t.Description, mark))
+		for _, line := range strings.Split(t.Notes, "\n") {
+			if query.Found(line) {
+				printf(out, "%*s     %s\n", width, "", query.Highlight(strings.TrimSpace(line), mark))
+			}
+		}
+	}
+}
+
+// printTree shows the subtasks indented below their parent, with the
+// completion roll-up of the parents. The tasks whose parent is
+// not listed are shown at the top level
+func (f tableFormatter) printTree(out io.Writer, tasks []task.Task, progress map[uint]task.Progress) {
+	listed := map[uint]bool{}
+	for _, t := range tasks {
+		listed[t.ID] = true
+	}
+	children := map[uint][]task.Task{}
+	var roots []task.Task
+	for _, t := range tasks {
+		if t.ParentID != nil && listed[*t.ParentID] {
+			children[*t.ParentID] = append(children[*t.ParentID], t)
+			continue
+		}
+		roots = append(roots, t)
+	}
+
+	var ordered []task.Task
+	depth := map[uint]int{}
+	var walk func(ts []task.Task, d int)
+	walk = func(ts []task.Task, d int) {
+		for _, t := range ts {
+			ordered = append(ordered, t)
+			depth[t.ID] = d
+			walk(children[t.ID], d+1)
 		}
 	}
-	return IDs, task.IDs, nil
+	walk(roots, 0)
+
+	f.writeTasks(out, ordered, func(t task.Task) string {
+		desc := strings.Repeat("  ", depth[t.ID]) + t.Description
+		if p, ok := progress[t.ID]; ok {
+			desc += fmt.Sprintf(" [%d/%d]", p.Done, p.Total)
+		}
+		return desc
+	})
 }
 
-func printTasks(out io.Writer, tasks []task.Task) {
+// writeTasks writes the task table, describe gives the
+// text of the description column. With colors the overdue
+// tasks are red and the completed ones gray
+func (f tableFormatter) writeTasks(out io.Writer, tasks []task.Task, describe func(task.Task) string) {
 	if len(tasks) == 0 {
 		println(out, "No tasks found")
 		return
 	}
 
-	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	// the rows are painted once aligned, the tabwriter
+	// would count the color codes as part of the width
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
 
-	println(w, "ID\tStatus\tCreated At\tDescription")
+	println(w, "ID\tStatus\tPriority\tCreated At\tDue At\tRepeat\tProject\tDescription\tTags")
 	println(w, "------------------------------------------------")
 
+	now := time.Now()
 	for _, t := range tasks {
 		status := "·"
 		if t.CompletedAt != nil {
 			status = "✓"
 		}
 
+		priority := ""
+		if t.Priority != task.NoPriority {
+			priority = t.Priority.String()
+		}
+
+		due := ""
+		if t.DueAt != nil {
+			due = t.DueAt.Format(f.dateFormat)
+			if t.Overdue(now) {
+				due += " (overdue)"
+			}
+		}
+
+		project := ""
+		if t.Project != nil {
+			project = t.Project.Name
+		}
+
+		tags := make([]string, len(t.Tags))
+		for i, tag := range t.Tags {
+			tags[i] = "+" + tag.Name
+		}
+
 		printf(
 			w,
-			"%d\t%s\t%s\t%s\n",
+			"%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
 			t.ID,
 			status,
-			t.CreatedAt.Format("02/01/2006 15:04"),
-			t.Description,
+			priority,
+			t.CreatedAt.Format(f.dateFormat),
+			due,
+			t.Recurrence,
+			project,
+			describe(t),
+			strings.Join(tags, " "),
 		)
 	}
+	w.Flush()
+
+	lines := strings.SplitAfter(b.String(), "\n")
+	for i, line := range lines {
+		// the first two lines are the header
+		if i >= 2 && i-2 < len(tasks) {
+			line = f.paintTask(tasks[i-2], line, now)
+		}
+		printf(out, "%s", line)
+	}
+}
+
+// paintTask colors the row of t: red when it is
+// overdue and gray when it is completed
+func (f tableFormatter) paintTask(t task.Task, row string, now time.Time) string {
+	text, newline := strings.CutSuffix(row, "\n")
+	switch {
+	case t.Overdue(now):
+		text = f.paint("red", text)
+	case t.CompletedAt != nil:
+		text = f.paint("gray", text)
+	}
+	if newline {
+		text += "\n"
+	}
+	return text
+}
+
+// printTask shows every detail of a single task
+func (f tableFormatter) printTask(out io.Writer, t task.Task) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	printf(w, "ID:\t%d\n", t.ID)
+	printf(w, "Description:\t%s\n", t.Description)
+	printf(w, "Status:\t%s\n", status(t))
+	printf(w, "Priority:\t%s\n", t.Priority)
+	if t.Project != nil {
+		printf(w, "Project:\t%s\n", t.Project.Name)
+	}
+	if t.Context != "" {
+		printf(w, "Context:\t@%s\n", t.Context)
+	}
+	if len(t.Tags) > 0 {
+		tags := make([]string, len(t.Tags))
+		for i, tag := range t.Tags {
+			tags[i] = "+" + tag.Name
+		}
+		printf(w, "Tags:\t%s\n", strings.Join(tags, " "))
+	}
+	if t.ParentID != nil {
+		printf(w, "Parent:\t%d\n", *t.ParentID)
+	}
+	if t.Recurrence != "" {
+		printf(w, "Repeat:\t%s\n", t.Recurrence)
+	}
+	printf(w, "Created At:\t%s\n", t.CreatedAt.Format(f.dateFormat))
+	printf(w, "Updated At:\t%s\n", t.UpdatedAt.Format(f.dateFormat))
+	for _, d := range []struct {
+		name string
+		at   *time.Time
+	}{
+		{"Due At", t.DueAt},
+		{"Completed At", t.CompletedAt},
+		{"Removed At", t.DeletedAt},
+	} {
+		if d.at != nil {
+			printf(w, "%s:\t%s\n", d.name, d.at.Format(f.dateFormat))
+		}
+	}
+	w.Flush()
+
+	if t.Notes != "" {
+		println(out, "\nNotes:")
+		for _, line := range strings.Split(t.Notes, "\n") {
+			println(out, "  "+line)
+		}
+	}
+	if len(t.Annotations) > 0 {
+		println(out, "\nAnnotations:")
+		for _, a := range t.Annotations {
+			printf(out, "  %s  %s\n", a.CreatedAt.Format(f.dateFormat), a.Text)
+		}
+	}
+}
+
+func printTags(out io.Writer, tags []task.TagCount) {
+	if len(tags) == 0 {
+		println(out, "No tags found")
+		return
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	println(w, "Tag\tTasks")
+	println(w, "--------------")
+
+	for _, t := range tags {
+		printf(w, "%s\t%d\n", t.Name, t.Count)
+	}
 
 	w.Flush()
 }
@@ -0,0 +1,168 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"arcedo/cli-todo/internal/config"
+	"arcedo/cli-todo/internal/task"
+)
+
+// templateFormatter renders each task with a text/template, see
+// templateFuncs for the helpers. The rest of the output is a table
+type templateFormatter struct {
+	tableFormatter
+	tmpl   *template.Template
+	errOut io.Writer
+}
+
+// newTemplateFormatter parses text, or the named template of the
+// config with that name, to write to out
+func newTemplateFormatter(text string, cfg config.Config, out, errOut io.Writer) (templateFormatter, error) {
+	if t, ok := cfg.Templates[text]; ok {
+		text = t
+	}
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	table := newTableFormatter(cfg, out)
+	tmpl, err := template.New("format").Funcs(templateFuncs(time.Now(), table)).Parse(text)
+	if err != nil {
+		return templateFormatter{}, usageErrorf("invalid format: %w", err)
+	}
+	return templateFormatter{tableFormatter: table, tmpl: tmpl, errOut: errOut}, nil
+}
+
+func (f templateFormatter) Tasks(out io.Writer, tasks []task.Task) {
+	if err := f.write(out, tasks); err != nil {
+		println(f.errOut, err)
+	}
+}
+
+// write renders the tasks, writing nothing unless
+// the template succeeds for every one of them
+func (f templateFormatter) write(out io.Writer, tasks []task.Task) error {
+	var b bytes.Buffer
+	for _, t := range tasks {
+		if err := f.tmpl.Execute(&b, t); err != nil {
+			return usageErrorf("invalid format: %w", err)
+		}
+	}
+	_, err := b.WriteTo(out)
+	return err
+}
+
+func (f templateFormatter) Task(out io.Writer, t task.Task) {
+	f.Tasks(out, []task.Task{t})
+}
+
+// templateFuncs are the helpers of the templates, with the relative
+// dates computed from now and the dates and colors of table:
+//
+//	date .DueAt          the date in the default format, empty when nil
+//	relative .DueAt      "in 3 days", "2 hours ago"..., empty when nil
+//	truncate 20 .Description
+//	pad 20 .Description  pads with spaces on the right, padLeft on the left
+//	color "red" .Description
+//	tags .               the tags of the task, e.g. "+home +work"
+func templateFuncs(now time.Time, table tableFormatter) template.FuncMap {
+	return template.FuncMap{
+		"date": func(v any) string {
+			if t, ok := timeOf(v); ok {
+				return t.Format(table.dateFormat)
+			}
+			return ""
+		},
+		"relative": func(v any) string {
+			if t, ok := timeOf(v); ok {
+				return relative(t, now)
+			}
+			return ""
+		},
+		"truncate": func(n int, s string) string {
+			if utf8.RuneCountInString(s) <= n {
+				return s
+			}
+			if n <= 1 {
+				return string([]rune(s)[:n])
+			}
+			return string([]rune(s)[:n-1]) + "…"
+		},
+		"pad": func(n int, s string) string {
+			return s + strings.Repeat(" ", max(0, n-utf8.RuneCountInString(s)))
+		},
+		"padLeft": func(n int, s string) string {
+			return strings.Repeat(" ", max(0, n-utf8.RuneCountInString(s))) + s
+		},
+		"color": func(name, s string) (string, error) {
+			if _, ok := colors[name]; !ok {
+				return "", fmt.Errorf("unknown color %q", name)
+			}
+			return table.paint(name, s), nil
+		},
+		"tags": func(t task.Task) string {
+			tags := make([]string, len(t.Tags))
+			for i, tag := range t.Tags {
+				tags[i] = "+" + tag.Name
+			}
+			return strings.Join(tags, " ")
+		},
+	}
+}
+
+func timeOf(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t != nil {
+			return *t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// relative describes t from now in the largest unit, e.g. "in 3 days"
+func relative(t, now time.Time) string {
+	d := t.Sub(now)
+	future := d > 0
+	if !future {
+		d = -d
+	}
+	if d < time.Minute {
+		return "now"
+	}
+
+	day := 24 * time.Hour
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"year", 365 * day},
+		{"month", 30 * day},
+		{"week", 7 * day},
+		{"day", day},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+	for _, u := range units {
+		if d < u.size {
+			continue
+		}
+		n := int(d / u.size)
+		s := fmt.Sprintf("%d %s", n, u.name)
+		if n > 1 {
+			s += "s"
+		}
+		if future {
+			return "in " + s
+		}
+		return s + " ago"
+	}
+	return "now"
+}
@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"arcedo/cli-todo/internal/tui"
+)
+
+func (c *CLI) tuiCommand() *command {
+	return &command{
+		name:    "tui",
+		summary: "Open the interactive full-screen interface",
+		help: "Keys: j/k or arrows move, a add, e edit, c or space complete,\n" +
+			"d delete, R restore, f cycle the filter, s cycle the sort, S reverse\n" +
+			"the sort, / search, Esc clear the search, r reload, ? help, q quit.\n" +
+			"The list is refreshed when the database changes.",
+		setup: noFlags(func(ctx context.Context, args []string) error {
+			out, ok := c.out.(*os.File)
+			if !ok {
+				return &exitError{ExitFailure, fmt.Errorf("failed to start the UI: %w", tui.ErrNotTerminal)}
+			}
+			err := tui.Run(ctx, c.taskService, os.Stdin, out, tui.Options{
+				DateFormat: c.config.DateFormat,
+				Filter:     c.config.ListFilter,
+				Sort:       c.config.ListSort,
+				Watch:      c.location.Path,
+			})
+			if errors.Is(err, tui.ErrNotTerminal) {
+				return &exitError{ExitFailure, err}
+			}
+			return err
+		}),
+	}
+}
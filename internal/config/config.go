@@ -0,0 +1,342 @@
+// Package config loads the user preferences from the config file
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"arcedo/cli-todo/internal/task"
+)
+
+// DefaultDateFormat is the layout of the dates shown in the tables
+const DefaultDateFormat = "02/01/2006 15:04"
+
+// Config holds the user preferences
+type Config struct {
+	// Path is the file the config was loaded from
+	Path string
+	// DB is the path of the database, see db.Locate
+	DB string
+	// DateFormat is the Go layout of the dates shown in the tables
+	DateFormat string
+	// ListFilter is the filter of list without arguments
+	ListFilter task.ListFilter
+	// ListSort is the order of list without --sort, by ID when empty
+	ListSort task.ListOrderValue
+	// Color enables the colors of the output
+	Color bool
+	// ColorSet reports whether Color was set in the config
+	// rather than left to its default
+	ColorSet bool
+	// Aliases holds the commands run in place of each alias
+	Aliases map[string]string
+	// Templates holds the named templates of list --format
+	Templates map[string]string
+}
+
+var (
+	ErrInvalidConfig = errors.New("invalid config")
+	ErrUnknownKey    = errors.New("unknown config key")
+)
+
+// Default returns the config used when there is no config file
+func Default() Config {
+	return Config{
+		DateFormat: DefaultDateFormat,
+		ListFilter: task.Uncompleted,
+		Color:      true,
+		Aliases:    map[string]string{},
+		Templates:  map[string]string{},
+	}
+}
+
+// setting is a key of the config file with a single value
+type setting struct {
+	key  string
+	help string
+	get  func(c Config) string
+	set  func(c *Config, value string) error
+	// boolean values are written as TOML booleans, not strings
+	boolean bool
+}
+
+// settings holds every key of the config file but the
+// aliases.<name> and templates.<name> keys, in the listing order
+var settings = []setting{
+	{
+		key:  "db",
+		help: "path of the database",
+		get:  func(c Config) string { return c.DB },
+		set: func(c *Config, value string) error {
+			c.DB = value
+			return nil
+		},
+	},
+	{
+		key:  "date.format",
+		help: "Go layout of the dates, e.g. 2006-01-02 15:04",
+		get:  func(c Config) string { return c.DateFormat },
+		set: func(c *Config, value string) error {
+			if value == "" {
+				return fmt.Errorf("empty date format")
+			}
+			c.DateFormat = value
+			return nil
+		},
+	},
+	{
+		key:  "list.filter",
+		help: "filter of list without arguments, e.g. uncompleted or ready",
+		get:  func(c Config) string { return string(c.ListFilter) },
+		set: func(c *Config, value string) error {
+			if !slices.Contains(task.ListFilters, task.ListFilter(value)) {
+				return fmt.Errorf("invalid list filter %q", value)
+			}
+			c.ListFilter = task.ListFilter(value)
+			return nil
+		},
+	},
+	{
+		key:  "list.sort",
+		help: "sort field of list without --sort, e.g. due or priority",
+		get:  func(c Config) string { return string(c.ListSort) },
+		set: func(c *Config, value string) error {
+			if value != "" && !slices.Contains(task.ListOrderValues, task.ListOrderValue(value)) {
+				return fmt.Errorf("invalid sort field %q", value)
+			}
+			c.ListSort = task.ListOrderValue(value)
+			return nil
+		},
+	},
+	{
+		key:     "color",
+		help:    "colored output, true or false",
+		boolean: true,
+		get:     func(c Config) string { return strconv.FormatBool(c.Color) },
+		set: func(c *Config, value string) (err error) {
+			c.ColorSet = true
+			switch value {
+			case "on":
+				c.Color = true
+			case "off":
+				c.Color = false
+			default:
+				if c.Color, err = strconv.ParseBool(value); err != nil {
+					return fmt.Errorf("invalid color %q, must be true or false", value)
+				}
+			}
+			return nil
+		},
+	},
+}
+
+// Set changes the value of key, checking it is valid
+func (c *Config) Set(key, value string) error {
+	if name, ok := strings.CutPrefix(key, "aliases."); ok && name != "" {
+		if c.Aliases == nil {
+			c.Aliases = map[string]string{}
+		}
+		c.Aliases[name] = value
+		return nil
+	}
+	if name, ok := strings.CutPrefix(key, "templates."); ok && name != "" {
+		if c.Templates == nil {
+			c.Templates = map[string]string{}
+		}
+		c.Templates[name] = value
+		return nil
+	}
+	for _, s := range settings {
+		if s.key == key {
+			if err := s.set(c, value); err != nil {
+				return fmt.Errorf("%w: %s: %w", ErrInvalidConfig, key, err)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%w %q", ErrUnknownKey, key)
+}
+
+// Get returns the value of key, the default when it is not set
+func (c Config) Get(key string) (string, error) {
+	if name, ok := strings.CutPrefix(key, "aliases."); ok {
+		if value, ok := c.Aliases[name]; ok {
+			return value, nil
+		}
+		return "", fmt.Errorf("%w %q, no such alias", ErrUnknownKey, key)
+	}
+	if name, ok := strings.CutPrefix(key, "templates."); ok {
+		if value, ok := c.Templates[name]; ok {
+			return value, nil
+		}
+		return "", fmt.Errorf("%w %q, no such template", ErrUnknownKey, key)
+	}
+	for _, s := range settings {
+		if s.key == key {
+			return s.get(c), nil
+		}
+	}
+	return "", fmt.Errorf("%w %q", ErrUnknownKey, key)
+}
+
+// Setting is a key of the config with its value
+type Setting struct {
+	Key   string
+	Value string
+	// Help describes the key, empty for the aliases and templates
+	Help    string
+	boolean bool
+}
+
+// TOML returns the line of the config file that sets the setting
+func (s Setting) TOML() string {
+	if s.boolean {
+		return s.Key + " = " + s.Value
+	}
+	return s.Key + " = " + encodeValue(s.Value)
+}
+
+// Settings returns every setting, followed by the aliases
+// and templates sorted by name
+func (c Config) Settings() []Setting {
+	var list []Setting
+	for _, s := range settings {
+		list = append(list, Setting{Key: s.key, Value: s.get(c), Help: s.help, boolean: s.boolean})
+	}
+	for _, m := range []struct {
+		prefix string
+		values map[string]string
+	}{
+		{"aliases.", c.Aliases},
+		{"templates.", c.Templates},
+	} {
+		names := make([]string, 0, len(m.values))
+		for name := range m.values {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			list = append(list, Setting{Key: m.prefix + name, Value: m.values[name]})
+		}
+	}
+	return list
+}
+
+// Path returns the path of the config file,
+// $XDG_CONFIG_HOME/cli-todo/config or ~/.config/cli-todo/config
+func Path() (string, error) {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find the config directory: %w", err)
+		}
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "cli-todo", "config"), nil
+}
+
+// Load reads the config file at path, a missing file is the default config
+func Load(path string) (Config, error) {
+	c := Default()
+	c.Path = path
+	f, err := read(path)
+	if err != nil {
+		return Config{}, err
+	}
+	for _, l := range f.lines {
+		if l.key == "" {
+			continue
+		}
+		if err := c.Set(l.key, l.value); err != nil {
+			if errors.Is(err, ErrUnknownKey) {
+				err = fmt.Errorf("%w: %w", ErrInvalidConfig, err)
+			}
+			return Config{}, fmt.Errorf("%s: line %d: %w", path, l.number, err)
+		}
+	}
+	return c, nil
+}
+
+// SetValue writes the value of key to the config file at path,
+// keeping the rest of the file as it is
+func SetValue(path, key, value string) error {
+	c, err := Load(path)
+	if err != nil {
+		return err
+	}
+	if err := c.Set(key, value); err != nil {
+		return err
+	}
+	f, err := read(path)
+	if err != nil {
+		return err
+	}
+	bare := false
+	for _, s := range settings {
+		if s.key == key && s.boolean {
+			// e.g. on is written as true
+			value, bare = s.get(c), true
+		}
+	}
+	f.set(key, value, bare)
+	return write(path, f)
+}
+
+// Unset removes key from the config file at path,
+// keeping the rest of the file as it is
+func Unset(path, key string) error {
+	f, err := read(path)
+	if err != nil {
+		return err
+	}
+	if !f.unset(key) {
+		return fmt.Errorf("%w %q, it is not set", ErrUnknownKey, key)
+	}
+	return write(path, f)
+}
+
+// read parses the config file at path, a missing file is empty
+func read(path string) (*file, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return &file{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	f, err := parse(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return f, nil
+}
+
+// write replaces the config file at path with f, creating its directory
+func write(path string, f *file) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create the config directory: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".config-*")
+	if err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(f.String()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"arcedo/cli-todo/internal/config"
+	"arcedo/cli-todo/internal/task"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `db = "~/tasks.db"
+
+# list templates
+[templates]
+short = "{{.ID}}\t{{.Description}}" # tab separated
+literal = '{{.ID}} "quoted"'
+bare = {{.ID}}
+`)
+
+	c, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.DB != "~/tasks.db" {
+		t.Errorf("expected db ~/tasks.db, got %q", c.DB)
+	}
+	want := map[string]string{
+		"short":   "{{.ID}}\t{{.Description}}",
+		"literal": `{{.ID}} "quoted"`,
+		"bare":    "{{.ID}}",
+	}
+	for name, tmpl := range want {
+		if c.Templates[name] != tmpl {
+			t.Errorf("template %s: expected %q, got %q", name, tmpl, c.Templates[name])
+		}
+	}
+}
+
+func TestLoad_Missing(t *testing.T) {
+	c, err := config.Load(filepath.Join(t.TempDir(), "config"))
+	if err != nil {
+		t.Fatalf("expected a missing file to be an empty config, got %v", err)
+	}
+	if len(c.Templates) != 0 {
+		t.Errorf("expected no templates, got %v", c.Templates)
+	}
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	for _, content := range []string{
+		"[templates\n",
+		"short\n",
+		"short = \"unclosed\n",
+		"short = \"a\" b\n",
+	} {
+		_, err := config.Load(writeConfig(t, content))
+		if !errors.Is(err, config.ErrInvalidConfig) {
+			t.Errorf("%q: expected invalid config error, got %v", content, err)
+		}
+	}
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", "/tmp/xdg")
+	path, err := config.Path()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if path != "/tmp/xdg/cli-todo/config" {
+		t.Errorf("unexpected path %q", path)
+	}
+}
+
+func TestLoad_Settings(t *testing.T) {
+	path := writeConfig(t, `color = off
+[date]
+format = "2006-01-02"
+[list]
+filter = ready
+sort = due
+`)
+
+	c, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Color || c.DateFormat != "2006-01-02" || c.ListFilter != task.Ready || c.ListSort != task.ByDue {
+		t.Errorf("unexpected config %+v", c)
+	}
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	c, err := config.Load(filepath.Join(t.TempDir(), "config"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !c.Color || c.DateFormat != config.DefaultDateFormat || c.ListFilter != task.Uncompleted {
+		t.Errorf("unexpected defaults %+v", c)
+	}
+}
+
+func TestLoad_InvalidSettings(t *testing.T) {
+	for _, content := range []string{
+		"color = maybe\n",
+		"[list]\nfilter = someday\n",
+		"[list]\nsort = size\n",
+		"unknown = 1\n",
+	} {
+		_, err := config.Load(writeConfig(t, content))
+		if !errors.Is(err, config.ErrInvalidConfig) {
+			t.Errorf("%q: expected invalid config error, got %v", content, err)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	c := config.Default()
+	c.Templates["short"] = "{{.ID}}"
+
+	tests := map[string]string{
+		"color":           "true",
+		"date.format":     config.DefaultDateFormat,
+		"list.filter":     "uncompleted",
+		"templates.short": "{{.ID}}",
+	}
+	for key, want := range tests {
+		got, err := c.Get(key)
+		if err != nil || got != want {
+			t.Errorf("%s: expected %q, got %q (%v)", key, want, got, err)
+		}
+	}
+	for _, key := range []string{"nope", "templates.long"} {
+		if _, err := c.Get(key); !errors.Is(err, config.ErrUnknownKey) {
+			t.Errorf("%s: expected unknown key error, got %v", key, err)
+		}
+	}
+}
+
+func TestSetValue_KeepsComments(t *testing.T) {
+	path := writeConfig(t, `# my config
+color = true # no colors on the server
+
+# list templates
+[templates]
+short = "{{.ID}}"
+`)
+
+	for _, kv := range [][2]string{
+		{"color", "false"},
+		{"db", "~/tasks.db"},
+		{"templates.long", "{{.ID}} {{.Description}}"},
+		{"list.sort", "due"},
+	} {
+		if err := config.SetValue(path, kv[0], kv[1]); err != nil {
+			t.Fatalf("failed to set %s: %v", kv[0], err)
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	want := `# my config
+color = false # no colors on the server
+db = "~/tasks.db"
+
+# list templates
+[templates]
+short = "{{.ID}}"
+long = "{{.ID}} {{.Description}}"
+
+[list]
+sort = "due"
+`
+	if string(b) != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, b)
+	}
+}
+
+func TestSetValue_Encoding(t *testing.T) {
+	path := writeConfig(t, "")
+	template := "{{.ID}}\t\"{{.Description}}\" \\ \x00\a\n"
+
+	for _, kv := range [][2]string{{"color", "off"}, {"templates.odd", template}} {
+		if err := config.SetValue(path, kv[0], kv[1]); err != nil {
+			t.Fatalf("failed to set %s: %v", kv[0], err)
+		}
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	want := `color = false
+
+[templates]
+odd = "{{.ID}}\t\"{{.Description}}\" \\ \u0000\u0007\n"
+`
+	if string(b) != want {
+		t.Errorf("expected:\n%s\ngot:\n%s", want, b)
+	}
+	c, err := config.Load(path)
+	if err != nil || c.Color || c.Templates["odd"] != template {
+		t.Errorf("expected the values read back, got %v, %q (%v)", c.Color, c.Templates["odd"], err)
+	}
+}
+
+func TestSetValue_Invalid(t *testing.T) {
+	path := writeConfig(t, "# empty\n")
+
+	if err := config.SetValue(path, "color", "maybe"); !errors.Is(err, config.ErrInvalidConfig) {
+		t.Errorf("expected invalid config error, got %v", err)
+	}
+	if err := config.SetValue(path, "nope", "1"); !errors.Is(err, config.ErrUnknownKey) {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+	if b, _ := os.ReadFile(path); string(b) != "# empty\n" {
+		t.Errorf("expected the file to be unchanged, got %q", b)
+	}
+}
+
+func TestSetValue_NewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cli-todo", "config")
+	if err := config.SetValue(path, "date.format", "2006-01-02"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	c, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.DateFormat != "2006-01-02" {
+		t.Errorf("expected the new date format, got %q", c.DateFormat)
+	}
+}
+
+func TestUnset(t *testing.T) {
+	path := writeConfig(t, `[aliases]
+ls = "list all" # everything
+rm = remove
+`)
+
+	if err := config.Unset(path, "aliases.ls"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := config.Unset(path, "aliases.ls"); !errors.Is(err, config.ErrUnknownKey) {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read config: %v", err)
+	}
+	if string(b) != "[aliases]\nrm = remove\n" {
+		t.Errorf("unexpected config %q", b)
+	}
+}
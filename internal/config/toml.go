@@ -0,0 +1,236 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The config file is a subset of TOML: comments, [sections] and
+// key = value lines, where the values are strings, quoted or not, or
+// booleans.
+// The file is kept line by line so it can be written back as it was
+
+// file is a parsed config file
+type file struct {
+	lines []line
+}
+
+// line is a line of the file, only key and value are set for the
+// lines with a value. The key includes the section, e.g. "templates.short"
+type line struct {
+	text   string
+	number int
+	key    string
+	value  string
+	// section is the section the line belongs to
+	section string
+	// comment is the trailing comment of a value, if any
+	comment string
+}
+
+func parse(s string) (*file, error) {
+	f := &file{}
+	section := ""
+	if s == "" {
+		return f, nil
+	}
+	for i, text := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		l := line{text: text, number: i + 1}
+		trimmed := strings.TrimSpace(text)
+		switch {
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+		case strings.HasPrefix(trimmed, "["):
+			name, ok := strings.CutSuffix(stripComment(trimmed), "]")
+			name = strings.TrimSpace(strings.TrimPrefix(name, "["))
+			if !ok || !validKey(name) {
+				return nil, fmt.Errorf("%w: line %d: invalid section %q", ErrInvalidConfig, i+1, trimmed)
+			}
+			section = name
+			l.section = name
+		default:
+			key, raw, ok := strings.Cut(trimmed, "=")
+			key = strings.TrimSpace(key)
+			if !ok || !validKey(key) {
+				return nil, fmt.Errorf("%w: line %d: expected 'key = value', got %q", ErrInvalidConfig, i+1, trimmed)
+			}
+			value, comment, err := decodeValue(strings.TrimSpace(raw))
+			if err != nil {
+				return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidConfig, i+1, err)
+			}
+			if section != "" {
+				key = section + "." + key
+			}
+			l.key, l.value, l.comment = key, value, comment
+		}
+		if l.section == "" {
+			l.section = section
+		}
+		f.lines = append(f.lines, l)
+	}
+	return f, nil
+}
+
+// set changes the value of key in the line that sets it, keeping its
+// comment, unquoted when bare. A new key goes after the last value of
+// its section, the section being what comes before the first dot, e.g.
+// "list" for "list.sort". The section is added at the end when it is
+// missing
+func (f *file) set(key, value string, bare bool) {
+	encoded := value
+	if !bare {
+		encoded = encodeValue(value)
+	}
+	for i := len(f.lines) - 1; i >= 0; i-- {
+		l := &f.lines[i]
+		if l.key != key {
+			continue
+		}
+		name := strings.TrimSpace(strings.SplitN(l.text, "=", 2)[0])
+		indent := l.text[:len(l.text)-len(strings.TrimLeft(l.text, " \t"))]
+		l.value = value
+		l.text = indent + name + " = " + encoded
+		if l.comment != "" {
+			l.text += " " + l.comment
+		}
+		return
+	}
+
+	section, name, ok := strings.Cut(key, ".")
+	if !ok {
+		section, name = "", key
+	}
+	l := line{key: key, value: value, section: section, text: name + " = " + encoded}
+	at := -1
+	for i, existing := range f.lines {
+		if existing.section == section && (existing.key != "" || at < 0 && section != "") {
+			at = i + 1
+		}
+	}
+	first := slices.IndexFunc(f.lines, func(l line) bool { return l.section != "" })
+	switch {
+	case at >= 0:
+		f.lines = slices.Insert(f.lines, at, l)
+	case section == "" && first >= 0:
+		// the top-level values go before the first section
+		f.lines = slices.Insert(f.lines, first, l, line{})
+	case section == "":
+		f.lines = append(f.lines, l)
+	default:
+		if len(f.lines) > 0 && strings.TrimSpace(f.lines[len(f.lines)-1].text) != "" {
+			f.lines = append(f.lines, line{})
+		}
+		f.lines = append(f.lines, line{text: "[" + section + "]", section: section}, l)
+	}
+}
+
+// unset removes the lines that set key,
+// reporting whether there was any
+func (f *file) unset(key string) bool {
+	n := len(f.lines)
+	f.lines = slices.DeleteFunc(f.lines, func(l line) bool { return l.key == key })
+	return len(f.lines) < n
+}
+
+func (f *file) String() string {
+	var b strings.Builder
+	for _, l := range f.lines {
+		b.WriteString(l.text + "\n")
+	}
+	return b.String()
+}
+
+func validKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeValue decodes a basic "string", a literal 'string'
+// or a bare value, each of them followed by an optional comment
+func decodeValue(raw string) (value, comment string, err error) {
+	switch {
+	case strings.HasPrefix(raw, `"`):
+		prefix, err := strconv.QuotedPrefix(raw)
+		if err != nil {
+			return "", "", fmt.Errorf("invalid string %s", raw)
+		}
+		if comment, err = trailingComment(raw[len(prefix):]); err != nil {
+			return "", "", err
+		}
+		value, err = strconv.Unquote(prefix)
+		return value, comment, err
+	case strings.HasPrefix(raw, "'"):
+		end := strings.Index(raw[1:], "'")
+		if end < 0 {
+			return "", "", fmt.Errorf("invalid string %s", raw)
+		}
+		comment, err = trailingComment(raw[end+2:])
+		return raw[1 : end+1], comment, err
+	}
+	if i := strings.Index(raw, "#"); i >= 0 {
+		comment = raw[i:]
+	}
+	value = stripComment(raw)
+	if value == "" {
+		return "", "", fmt.Errorf("missing value")
+	}
+	return value, comment, nil
+}
+
+// trailingComment returns the comment after a quoted value, if any
+func trailingComment(rest string) (string, error) {
+	rest = strings.TrimSpace(rest)
+	if rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected %q after the value", rest)
+	}
+	return rest, nil
+}
+
+// encodeValue quotes value as a basic string, escaping the quotes,
+// backslashes and control characters as TOML does
+func encodeValue(value string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range strings.ToValidUTF8(value, "\uFFFD") {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// stripComment removes a trailing comment from an unquoted text
+func stripComment(s string) string {
+	if i := strings.Index(s, "#"); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
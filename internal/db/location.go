@@ -0,0 +1,53 @@
+package db
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EnvPath is the environment variable with the path of the database
+const EnvPath = "CLI_TODO_DB"
+
+// Location is the path of the database and the reason it was chosen
+type Location struct {
+	Path   string
+	Source string
+}
+
+// Locate returns the path of the database given by the --db flag, then
+// by $CLI_TODO_DB, then by the config file, and otherwise the default
+// $XDG_DATA_HOME/cli-todo/tasks.db or ~/.local/share/cli-todo/tasks.db
+func Locate(flagPath, configPath string) (Location, error) {
+	var loc Location
+	switch {
+	case flagPath != "":
+		loc = Location{flagPath, "the --db flag"}
+	case os.Getenv(EnvPath) != "":
+		loc = Location{os.Getenv(EnvPath), "the " + EnvPath + " environment variable"}
+	case configPath != "":
+		loc = Location{configPath, "the db setting of the config file"}
+	default:
+		dir := os.Getenv("XDG_DATA_HOME")
+		source := "the default location in $XDG_DATA_HOME"
+		if dir == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return Location{}, fmt.Errorf("failed to find the data directory: %w", err)
+			}
+			dir = filepath.Join(home, ".local", "share")
+			source = "the default location"
+		}
+		return Location{filepath.Join(dir, "cli-todo", "tasks.db"), source}, nil
+	}
+
+	if rest, ok := strings.CutPrefix(loc.Path, "~/"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return Location{}, fmt.Errorf("failed to expand %s: %w", loc.Path, err)
+		}
+		loc.Path = filepath.Join(home, rest)
+	}
+	return loc, nil
+}
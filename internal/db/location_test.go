@@ -0,0 +1,54 @@
+package db_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"arcedo/cli-todo/internal/db"
+)
+
+func TestLocate(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	t.Setenv("XDG_DATA_HOME", "/data")
+
+	tests := []struct {
+		name       string
+		env        string
+		flag       string
+		configured string
+		want       db.Location
+	}{
+		{"flag", "env.db", "flag.db", "config.db", db.Location{"flag.db", "the --db flag"}},
+		{"env", "env.db", "", "config.db", db.Location{"env.db", "the CLI_TODO_DB environment variable"}},
+		{"config", "", "", "~/config.db", db.Location{filepath.Join(home, "config.db"), "the db setting of the config file"}},
+		{"default", "", "", "", db.Location{"/data/cli-todo/tasks.db", "the default location in $XDG_DATA_HOME"}},
+	}
+	for _, tt := range tests {
+		t.Setenv(db.EnvPath, tt.env)
+		got, err := db.Locate(tt.flag, tt.configured)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tt.name, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestConnectSqlite_CreatesDirectory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a", "b", "tasks.db")
+	database, err := db.ConnectSqlite(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("expected the database to be created: %v", err)
+	}
+}
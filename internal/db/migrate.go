@@ -1,13 +1,86 @@
 package db
 
 import (
+	"fmt"
+
 	"arcedo/cli-todo/internal/task"
 
 	"gorm.io/gorm"
 )
 
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
+		&task.Project{},
 		&task.Task{},
+		&task.Tag{},
+		&task.Dependency{},
+		&task.Annotation{},
 	)
+	if err != nil {
+		return err
+	}
+	return migrateSearch(db)
+}
+
+// searchTriggers keep the full-text index in sync with the tasks,
+// following the external content tables of the FTS5 documentation
+var searchTriggers = map[string]string{
+	"insert": `AFTER INSERT ON tasks BEGIN
+	INSERT INTO %[1]s(rowid, description, notes) VALUES (new.id, new.description, new.notes);
+END`,
+	"delete": `AFTER DELETE ON tasks BEGIN
+	INSERT INTO %[1]s(%[1]s, rowid, description, notes) VALUES ('delete', old.id, old.description, old.notes);
+END`,
+	"update": `AFTER UPDATE OF description, notes ON tasks BEGIN
+	INSERT INTO %[1]s(%[1]s, rowid, description, notes) VALUES ('delete', old.id, old.description, old.notes);
+	INSERT INTO %[1]s(rowid, description, notes) VALUES (new.id, new.description, new.notes);
+END`,
+}
+
+// migrateSearch creates the FTS5 index of the descriptions and notes
+// with its triggers, when sqlite has FTS5 (go-sqlite3 needs the
+// sqlite_fts5 build tag). Otherwise it drops the triggers, the tasks
+// could not be written without FTS5, and the search uses LIKE.
+// The index is rebuilt when the triggers are created, as the tasks
+// may have changed while they were missing
+func migrateSearch(db *gorm.DB) error {
+	var fts5 bool
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+		return fmt.Errorf("failed to check the FTS5 support: %w", err)
+	}
+	if !fts5 {
+		for name := range searchTriggers {
+			if err := db.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS %s_%s", task.SearchTable, name)).Error; err != nil {
+				return fmt.Errorf("failed to drop the search triggers: %w", err)
+			}
+		}
+		return nil
+	}
+
+	var count int64
+	err := db.Table("sqlite_master").Where("type = 'trigger' AND name LIKE ?", task.SearchTable+"_%").Count(&count).Error
+	if err != nil || count == int64(len(searchTriggers)) {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(fmt.Sprintf(
+			"CREATE VIRTUAL TABLE IF NOT EXISTS %s USING fts5(description, notes, "+
+				"content='tasks', content_rowid='id', tokenize='unicode61 remove_diacritics 0')",
+			task.SearchTable,
+		)).Error
+		if err != nil {
+			return fmt.Errorf("failed to create the search index: %w", err)
+		}
+		for name, body := range searchTriggers {
+			trigger := fmt.Sprintf("%s_%s", task.SearchTable, name)
+			err := tx.Exec(fmt.Sprintf("DROP TRIGGER IF EXISTS %s", trigger)).Error
+			if err == nil {
+				err = tx.Exec(fmt.Sprintf("CREATE TRIGGER %s %s", trigger, fmt.Sprintf(body, task.SearchTable))).Error
+			}
+			if err != nil {
+				return fmt.Errorf("failed to create the search triggers: %w", err)
+			}
+		}
+		return tx.Exec(fmt.Sprintf("INSERT INTO %[1]s(%[1]s) VALUES ('rebuild')", task.SearchTable)).Error
+	})
 }
@@ -2,11 +2,22 @@
 package db
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
+// ConnectSqlite opens the database at path,
+// creating its directory if it does not exist
 func ConnectSqlite(path string) (*gorm.DB, error) {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create the database directory: %w", err)
+		}
+	}
 	db, err := gorm.Open(
 		sqlite.Open(path),
 		&gorm.Config{},
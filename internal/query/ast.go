@@ -0,0 +1,143 @@
+// Package query parses the filter expressions of the list command, e.g.
+//
+//	status:open and (tag:work or priority>=high) and due<+7d
+//
+// into a syntax tree that is either translated to a SQL condition
+// or evaluated in memory against a task
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidQuery = errors.New("invalid filter")
+
+// Error is a query that cannot be parsed, Column points at the
+// character where it fails, counting from 1
+type Error struct {
+	Query  string
+	Column int
+	Msg    string
+}
+
+func errorf(query string, col int, format string, a ...any) *Error {
+	return &Error{Query: query, Column: col, Msg: fmt.Sprintf(format, a...)}
+}
+
+// Error shows the query with a caret below the failing column
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s at column %d: %s\n  %s\n  %s^",
+		ErrInvalidQuery, e.Column, e.Msg, e.Query, strings.Repeat(" ", e.Column-1))
+}
+
+func (e *Error) Unwrap() error {
+	return ErrInvalidQuery
+}
+
+// Op is a comparison operator, ':' is read as Eq
+type Op string
+
+const (
+	Eq       Op = "="
+	Ne       Op = "!="
+	Lt       Op = "<"
+	Le       Op = "<="
+	Gt       Op = ">"
+	Ge       Op = ">="
+	Contains Op = "~"
+)
+
+// Node is a node of the syntax tree, an And, Or, Not or Comparison
+type Node interface {
+	fmt.Stringer
+	// Column is where the node starts in the query
+	Column() int
+}
+
+// And and Or join two nodes
+type And struct{ Left, Right Node }
+type Or struct{ Left, Right Node }
+
+type Not struct {
+	X   Node
+	Col int
+}
+
+// Comparison compares a field of the tasks with a value
+type Comparison struct {
+	// Field is the canonical name of the field, e.g. "description" for "desc"
+	Field string
+	Op    Op
+	// Value is unquoted, lowercased for the status, context and tag,
+	// the last two without their '@' or '+' prefix
+	Value string
+	Col   int
+
+	// the value resolved for the field
+	none bool
+	num  int
+	date date
+}
+
+func (n And) Column() int        { return n.Left.Column() }
+func (n Or) Column() int         { return n.Left.Column() }
+func (n Not) Column() int        { return n.Col }
+func (n Comparison) Column() int { return n.Col }
+
+func (n And) String() string { return "(" + n.Left.String() + " and " + n.Right.String() + ")" }
+func (n Or) String() string  { return "(" + n.Left.String() + " or " + n.Right.String() + ")" }
+func (n Not) String() string { return "not " + n.X.String() }
+
+func (n Comparison) String() string {
+	return fmt.Sprintf("%s%s%q", n.Field, n.Op, n.Value)
+}
+
+// date is a day, either absolute or relative to the day of
+// the evaluation when abs is zero
+type date struct {
+	abs          time.Time
+	days, months int
+}
+
+// start returns the start of the day in the location of now
+func (d date) start(now time.Time) time.Time {
+	t := now.AddDate(0, d.months, d.days)
+	if !d.abs.IsZero() {
+		t = d.abs
+	}
+	y, m, day := t.Date()
+	return time.Date(y, m, day, 0, 0, 0, 0, now.Location())
+}
+
+// Query is a parsed filter expression. Unless it compares the status,
+// it only keeps the tasks that are not removed
+type Query struct {
+	Root Node
+	text string
+}
+
+func (q *Query) String() string {
+	return q.text
+}
+
+// uses reports whether the query compares the field
+func (q *Query) uses(field string) bool {
+	var walk func(n Node) bool
+	walk = func(n Node) bool {
+		switch n := n.(type) {
+		case And:
+			return walk(n.Left) || walk(n.Right)
+		case Or:
+			return walk(n.Left) || walk(n.Right)
+		case Not:
+			return walk(n.X)
+		case Comparison:
+			return n.Field == field
+		}
+		return false
+	}
+	return walk(q.Root)
+}
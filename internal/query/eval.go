@@ -0,0 +1,315 @@
+package query
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"arcedo/cli-todo/internal/task"
+)
+
+// kind tells how a field is compared
+type kind int
+
+const (
+	statusKind kind = iota
+	numberKind
+	priorityKind
+	textKind
+	contextKind
+	projectKind
+	tagKind
+	dateKind
+)
+
+var (
+	orderOps = []Op{Eq, Ne, Lt, Le, Gt, Ge}
+	textOps  = []Op{Eq, Ne, Contains}
+)
+
+// kindOps are the operators each kind of field accepts
+var kindOps = map[kind][]Op{
+	statusKind:   {Eq, Ne},
+	numberKind:   orderOps,
+	priorityKind: orderOps,
+	textKind:     textOps,
+	contextKind:  textOps,
+	projectKind:  textOps,
+	tagKind:      textOps,
+	dateKind:     orderOps,
+}
+
+var statuses = []string{"open", "completed", "removed"}
+
+// statusSQL are the conditions of each status
+var statusSQL = map[string]string{
+	"open":      "(completed_at IS NULL AND deleted_at IS NULL)",
+	"completed": "(completed_at IS NOT NULL AND deleted_at IS NULL)",
+	"removed":   "(deleted_at IS NOT NULL)",
+}
+
+type field struct {
+	name string
+	kind kind
+	// column is the column of the tasks table holding the field
+	column string
+	// nullable fields accept the value none
+	nullable bool
+	// text or date get the value of the field from a task
+	text func(t task.Task) string
+	date func(t task.Task) *time.Time
+}
+
+var fields = []field{
+	{name: "status", kind: statusKind},
+	{name: "id", kind: numberKind, column: "id"},
+	{name: "priority", kind: priorityKind, column: "priority"},
+	{name: "description", kind: textKind, column: "description", text: func(t task.Task) string { return t.Description }},
+	{name: "notes", kind: textKind, column: "notes", text: func(t task.Task) string { return t.Notes }},
+	{name: "project", kind: projectKind, column: "project_id", nullable: true},
+	{name: "context", kind: contextKind, column: "context", nullable: true, text: func(t task.Task) string { return t.Context }},
+	{name: "tag", kind: tagKind, column: "id", nullable: true},
+	{name: "due", kind: dateKind, column: "due_at", nullable: true, date: func(t task.Task) *time.Time { return t.DueAt }},
+	{name: "created", kind: dateKind, column: "created_at", date: func(t task.Task) *time.Time { return &t.CreatedAt }},
+	{name: "updated", kind: dateKind, column: "updated_at", date: func(t task.Task) *time.Time { return &t.UpdatedAt }},
+	{name: "completed", kind: dateKind, column: "completed_at", nullable: true, date: func(t task.Task) *time.Time { return t.CompletedAt }},
+}
+
+var aliases = map[string]string{
+	"desc": "description",
+	"pri":  "priority",
+	"tags": "tag",
+}
+
+// lookupField finds a field by its name or alias, ignoring the case
+func lookupField(name string) (field, bool) {
+	name = strings.ToLower(name)
+	if canonical, ok := aliases[name]; ok {
+		name = canonical
+	}
+	i := slices.IndexFunc(fields, func(f field) bool { return f.name == name })
+	if i < 0 {
+		return field{}, false
+	}
+	return fields[i], true
+}
+
+func fieldNames() []string {
+	names := make([]string, len(fields))
+	for i, f := range fields {
+		names[i] = f.name
+	}
+	return names
+}
+
+// SQL returns the condition of the query on the tasks table, with its
+// arguments. The relative days are resolved from the day of now.
+// Every comparison is either true or false, never NULL, so that not
+// keeps the tasks Match keeps
+func (q *Query) SQL(now time.Time) (string, []any) {
+	cond, args := toSQL(q.Root, now)
+	if !q.uses("status") {
+		cond = "deleted_at IS NULL AND " + cond
+	}
+	return "(" + cond + ")", args
+}
+
+func toSQL(n Node, now time.Time) (string, []any) {
+	switch n := n.(type) {
+	case And:
+		left, largs := toSQL(n.Left, now)
+		right, rargs := toSQL(n.Right, now)
+		return "(" + left + " AND " + right + ")", append(largs, rargs...)
+	case Or:
+		left, largs := toSQL(n.Left, now)
+		right, rargs := toSQL(n.Right, now)
+		return "(" + left + " OR " + right + ")", append(largs, rargs...)
+	case Not:
+		x, args := toSQL(n.X, now)
+		return "(NOT " + x + ")", args
+	case Comparison:
+		return n.sql(now)
+	}
+	return "(1 = 1)", nil
+}
+
+func (n Comparison) sql(now time.Time) (string, []any) {
+	if n.Op == Ne {
+		n.Op = Eq
+		cond, args := n.sql(now)
+		return "(NOT " + cond + ")", args
+	}
+	f, _ := lookupField(n.Field)
+	col := f.column
+	switch f.kind {
+	case statusKind:
+		return statusSQL[n.Value], nil
+	case numberKind, priorityKind:
+		return fmt.Sprintf("(%s %s ?)", col, n.Op), []any{n.num}
+	case textKind, contextKind:
+		if n.none {
+			return fmt.Sprintf("(%s = '')", col), nil
+		}
+		cond, arg := textSQL(col, n.Op, n.Value)
+		return "(" + cond + ")", []any{arg}
+	case projectKind:
+		if n.none {
+			return "(project_id IS NULL)", nil
+		}
+		cond, arg := textSQL("name", n.Op, n.Value)
+		return "(project_id IS NOT NULL AND project_id IN (SELECT id FROM projects WHERE " + cond + "))", []any{arg}
+	case tagKind:
+		if n.none {
+			return "(id NOT IN (SELECT task_id FROM task_tags))", nil
+		}
+		cond, arg := textSQL("tags.name", n.Op, n.Value)
+		return "(id IN (SELECT task_tags.task_id FROM task_tags " +
+			"JOIN tags ON tags.id = task_tags.tag_id WHERE " + cond + "))", []any{arg}
+	case dateKind:
+		if n.none {
+			return fmt.Sprintf("(%s IS NULL)", col), nil
+		}
+		start := n.date.start(now)
+		end := start.AddDate(0, 0, 1)
+		switch n.Op {
+		case Eq:
+			return fmt.Sprintf("(%[1]s IS NOT NULL AND %[1]s >= ? AND %[1]s < ?)", col), []any{start, end}
+		case Lt:
+			return fmt.Sprintf("(%[1]s IS NOT NULL AND %[1]s < ?)", col), []any{start}
+		case Le:
+			return fmt.Sprintf("(%[1]s IS NOT NULL AND %[1]s < ?)", col), []any{end}
+		case Gt:
+			return fmt.Sprintf("(%[1]s IS NOT NULL AND %[1]s >= ?)", col), []any{end}
+		case Ge:
+			return fmt.Sprintf("(%[1]s IS NOT NULL AND %[1]s >= ?)", col), []any{start}
+		}
+	}
+	return "(1 = 1)", nil
+}
+
+// textSQL compares the column with =, or with LIKE for Contains
+func textSQL(col string, op Op, value string) (string, any) {
+	if op == Contains {
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value) + "%"
+		return col + ` LIKE ? ESCAPE '\'`, pattern
+	}
+	return col + " = ?", value
+}
+
+// Match reports whether the task meets the query, evaluated in memory
+// like SQL would, for the backends without SQL. The Project and the
+// Tags of the task must be loaded
+func (q *Query) Match(t task.Task, now time.Time) bool {
+	if !q.uses("status") && t.DeletedAt != nil {
+		return false
+	}
+	return match(q.Root, t, now)
+}
+
+func match(n Node, t task.Task, now time.Time) bool {
+	switch n := n.(type) {
+	case And:
+		return match(n.Left, t, now) && match(n.Right, t, now)
+	case Or:
+		return match(n.Left, t, now) || match(n.Right, t, now)
+	case Not:
+		return !match(n.X, t, now)
+	case Comparison:
+		return n.match(t, now)
+	}
+	return true
+}
+
+func (n Comparison) match(t task.Task, now time.Time) bool {
+	if n.Op == Ne {
+		n.Op = Eq
+		return !n.match(t, now)
+	}
+	f, _ := lookupField(n.Field)
+	switch f.kind {
+	case statusKind:
+		switch {
+		case t.DeletedAt != nil:
+			return n.Value == "removed"
+		case t.CompletedAt != nil:
+			return n.Value == "completed"
+		}
+		return n.Value == "open"
+	case numberKind:
+		return compare(int(t.ID), n.Op, n.num)
+	case priorityKind:
+		return compare(int(t.Priority), n.Op, n.num)
+	case textKind, contextKind:
+		if n.none {
+			return f.text(t) == ""
+		}
+		return matchText(f.text(t), n.Op, n.Value)
+	case projectKind:
+		if n.none {
+			return t.Project == nil
+		}
+		return t.Project != nil && matchText(t.Project.Name, n.Op, n.Value)
+	case tagKind:
+		if n.none {
+			return len(t.Tags) == 0
+		}
+		return slices.ContainsFunc(t.Tags, func(tag task.Tag) bool {
+			return matchText(tag.Name, n.Op, n.Value)
+		})
+	case dateKind:
+		d := f.date(t)
+		if n.none || d == nil {
+			return n.none && d == nil
+		}
+		start := n.date.start(now)
+		end := start.AddDate(0, 0, 1)
+		switch n.Op {
+		case Eq:
+			return !d.Before(start) && d.Before(end)
+		case Lt:
+			return d.Before(start)
+		case Le:
+			return d.Before(end)
+		case Gt:
+			return !d.Before(end)
+		case Ge:
+			return !d.Before(start)
+		}
+	}
+	return false
+}
+
+func compare(a int, op Op, b int) bool {
+	switch op {
+	case Eq:
+		return a == b
+	case Lt:
+		return a < b
+	case Le:
+		return a <= b
+	case Gt:
+		return a > b
+	case Ge:
+		return a >= b
+	}
+	return false
+}
+
+// matchText compares like textSQL, LIKE ignoring
+// the case of the ASCII letters only
+func matchText(s string, op Op, value string) bool {
+	if op == Contains {
+		return strings.Contains(asciiLower(s), asciiLower(value))
+	}
+	return s == value
+}
+
+func asciiLower(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'A' && r <= 'Z' {
+			return r + 'a' - 'A'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,90 @@
+package query
+
+import (
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokEnd tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+// token is a piece of a query, col is the column where it starts
+// counting the characters from 1. The strings are unquoted
+type token struct {
+	kind tokenKind
+	text string
+	col  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEnd:
+		return "end of the filter"
+	case tokString:
+		return `"` + t.text + `"`
+	}
+	return t.text
+}
+
+// opChars are the characters of the operators, they end a word
+const opChars = ":=!<>~"
+
+// lex splits s in words, double quoted strings, operators and
+// parentheses, ending with a tokEnd token past the last column
+func lex(s string) ([]token, error) {
+	var tokens []token
+	r := []rune(s)
+	for i := 0; i < len(r); {
+		col := i + 1
+		switch c := r[i]; {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", col})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", col})
+			i++
+		case c == '"':
+			var b strings.Builder
+			i++
+			for ; i < len(r) && r[i] != '"'; i++ {
+				if r[i] == '\\' && i+1 < len(r) {
+					i++
+				}
+				b.WriteRune(r[i])
+			}
+			if i == len(r) {
+				return nil, errorf(s, col, `missing closing "`)
+			}
+			tokens = append(tokens, token{tokString, b.String(), col})
+			i++
+		case strings.ContainsRune(opChars, c):
+			op := string(c)
+			if i+1 < len(r) && r[i+1] == '=' && strings.ContainsRune("!<>", c) {
+				op += "="
+			}
+			if op == "!" {
+				return nil, errorf(s, col, "unexpected '!', did you mean '!='?")
+			}
+			tokens = append(tokens, token{tokOp, op, col})
+			i += len(op)
+		default:
+			end := i
+			for end < len(r) && !unicode.IsSpace(r[end]) && !strings.ContainsRune(`()"`+opChars, r[end]) {
+				end++
+			}
+			tokens = append(tokens, token{tokWord, string(r[i:end]), col})
+			i = end
+		}
+	}
+	return append(tokens, token{tokEnd, "", len(r) + 1}), nil
+}
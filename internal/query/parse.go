@@ -0,0 +1,252 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"arcedo/cli-todo/internal/task"
+)
+
+// Parse parses a filter expression. Its comparisons are written
+// field op value, with no spaces needed around the operator:
+//
+//   - status: open, completed or removed
+//   - id and priority: a number, or none, low, medium or high
+//   - description (desc), notes, project and context: a word or a
+//     double quoted string, the context with or without its '@'
+//   - tag (tags): a tag with or without its '+'
+//   - due, created, updated and completed: YYYY-MM-DD, DD/MM/YYYY, today,
+//     tomorrow, yesterday or a number of days, weeks, months or years
+//     from today, e.g. +7d, -2w, +1m or -1y
+//
+// The operators are = (or :), != and, but for the status and texts,
+// <, <=, > and >=. ~ keeps the texts containing the value, ignoring
+// the case of ASCII letters. The dates compare whole days, due<+7d are
+// the tasks due before the day a week from today and created=-1d the
+// ones created yesterday. The value none matches a missing project,
+// context, tag, due or completion date.
+//
+// The comparisons are joined with and, or and not, and is implied
+// between two of them and binds tighter than or. Parentheses group them
+func Parse(s string) (*Query, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := parser{query: s, tokens: tokens}
+	if p.peek().kind == tokEnd {
+		return nil, errorf(s, 1, "empty filter")
+	}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEnd {
+		return nil, errorf(s, tok.col, "unexpected %s", tok)
+	}
+	return &Query{Root: root, text: s}, nil
+}
+
+type parser struct {
+	query  string
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEnd {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the word kw, ignoring the case
+func (p *parser) keyword(kw string) bool {
+	tok := p.peek()
+	return tok.kind == tokWord && strings.EqualFold(tok.text, kw)
+}
+
+// or := and ("or" and)*
+func (p *parser) or() (Node, error) {
+	left, err := p.and()
+	for err == nil && p.keyword("or") {
+		p.next()
+		var right Node
+		if right, err = p.and(); err == nil {
+			left = Or{left, right}
+		}
+	}
+	return left, err
+}
+
+// and := unary (["and"] unary)*
+func (p *parser) and() (Node, error) {
+	left, err := p.unary()
+	for err == nil {
+		switch tok := p.peek(); {
+		case tok.kind == tokEnd || tok.kind == tokRParen || p.keyword("or"):
+			return left, nil
+		case p.keyword("and"):
+			p.next()
+		}
+		var right Node
+		if right, err = p.unary(); err == nil {
+			left = And{left, right}
+		}
+	}
+	return nil, err
+}
+
+// unary := "not" unary | "(" or ")" | comparison
+func (p *parser) unary() (Node, error) {
+	tok := p.peek()
+	switch {
+	case p.keyword("not"):
+		p.next()
+		x, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return Not{x, tok.col}, nil
+	case tok.kind == tokLParen:
+		p.next()
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if end := p.peek(); end.kind != tokRParen {
+			return nil, errorf(p.query, end.col, "expected ) to close the ( at column %d, found %s", tok.col, end)
+		}
+		p.next()
+		return node, nil
+	}
+	return p.comparison()
+}
+
+// comparison := field op value
+func (p *parser) comparison() (Node, error) {
+	tok := p.next()
+	switch {
+	case tok.kind == tokEnd:
+		return nil, errorf(p.query, tok.col, "expected a comparison, e.g. status:open")
+	case tok.kind != tokWord || strings.EqualFold(tok.text, "and") || strings.EqualFold(tok.text, "or"):
+		return nil, errorf(p.query, tok.col, "expected a field, found %s", tok)
+	}
+	f, ok := lookupField(tok.text)
+	if !ok {
+		return nil, errorf(p.query, tok.col, "unknown field %q, must be one of %s", tok.text, strings.Join(fieldNames(), ", "))
+	}
+	n := Comparison{Field: f.name, Col: tok.col}
+
+	opTok := p.next()
+	if opTok.kind != tokOp {
+		return nil, errorf(p.query, opTok.col, "expected an operator after %s, found %s", tok.text, opTok)
+	}
+	n.Op = Op(opTok.text)
+	if n.Op == ":" {
+		n.Op = Eq
+	}
+	if !slices.Contains(kindOps[f.kind], n.Op) {
+		return nil, errorf(p.query, opTok.col, "%s cannot be compared with %s", f.name, opTok.text)
+	}
+
+	valTok := p.next()
+	if valTok.kind != tokWord && valTok.kind != tokString {
+		return nil, errorf(p.query, valTok.col, "expected a value after %s, found %s", opTok.text, valTok)
+	}
+	n.Value = valTok.text
+	if err := f.resolve(&n, valTok.kind == tokWord); err != nil {
+		return nil, errorf(p.query, valTok.col, "%s", err)
+	}
+	return n, nil
+}
+
+// resolve checks the value of n and converts it for the field,
+// the value none is only read when written as a word
+func (f field) resolve(n *Comparison, word bool) error {
+	if word && f.nullable && strings.EqualFold(n.Value, "none") {
+		if n.Op != Eq && n.Op != Ne {
+			return fmt.Errorf("%s can only be compared with none using =, : or !=", f.name)
+		}
+		n.none = true
+		return nil
+	}
+	switch f.kind {
+	case statusKind:
+		n.Value = strings.ToLower(n.Value)
+		if !slices.Contains(statuses, n.Value) {
+			return fmt.Errorf("invalid status %q, must be %s", n.Value, strings.Join(statuses, ", "))
+		}
+	case numberKind:
+		id, err := strconv.Atoi(n.Value)
+		if err != nil || id < 0 {
+			return fmt.Errorf("invalid %s %q, must be a number", f.name, n.Value)
+		}
+		n.num = id
+	case priorityKind:
+		p, err := task.ParsePriority(n.Value)
+		if err != nil {
+			return fmt.Errorf("invalid priority %q, must be none, low, medium or high", n.Value)
+		}
+		n.num = int(p)
+	case contextKind:
+		n.Value = strings.ToLower(strings.TrimPrefix(n.Value, "@"))
+	case tagKind:
+		n.Value = strings.ToLower(strings.TrimPrefix(n.Value, "+"))
+	case dateKind:
+		d, err := parseDate(n.Value)
+		if err != nil {
+			return err
+		}
+		n.date = d
+	}
+	return nil
+}
+
+// relativeDate is a signed number of days, weeks, months or years
+var relativeDate = regexp.MustCompile(`^([+-]?)(\d+)([dwmy])$`)
+
+func parseDate(s string) (date, error) {
+	switch strings.ToLower(s) {
+	case "today":
+		return date{}, nil
+	case "tomorrow":
+		return date{days: 1}, nil
+	case "yesterday":
+		return date{days: -1}, nil
+	}
+	if m := relativeDate.FindStringSubmatch(strings.ToLower(s)); m != nil {
+		n, err := strconv.Atoi(m[2])
+		if err != nil {
+			return date{}, fmt.Errorf("invalid date %q", s)
+		}
+		if m[1] == "-" {
+			n = -n
+		}
+		switch m[3] {
+		case "d":
+			return date{days: n}, nil
+		case "w":
+			return date{days: 7 * n}, nil
+		case "m":
+			return date{months: n}, nil
+		default:
+			return date{months: 12 * n}, nil
+		}
+	}
+	for _, layout := range []string{"2006-01-02", "02/01/2006"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return date{abs: t}, nil
+		}
+	}
+	return date{}, fmt.Errorf("invalid date %q, use YYYY-MM-DD, DD/MM/YYYY, today, tomorrow, yesterday or e.g. +7d, -2w, +1m", s)
+}
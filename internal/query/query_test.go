@@ -0,0 +1,201 @@
+package query_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"arcedo/cli-todo/internal/db"
+	"arcedo/cli-todo/internal/query"
+	"arcedo/cli-todo/internal/task"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"status:open", `status="open"`},
+		{"tag:x or tag:y tag:z", `(tag="x" or (tag="y" and tag="z"))`},
+		{"not STATUS:Open AND pri>=h", `(not status="open" and priority>="h")`},
+		{"(tag:x or tag:y) and not (due<today)", `((tag="x" or tag="y") and not due<"today")`},
+		{`desc~"say \"hi\"" notes!=none`, `(description~"say \"hi\"" and notes!="none")`},
+		{"Context:@Phone tag:+Work", `(context="phone" and tag="work")`},
+	}
+	for _, tt := range tests {
+		q, err := query.Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) unexpected error: %v", tt.in, err)
+			continue
+		}
+		if got := q.Root.String(); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		in     string
+		column int
+		msg    string
+	}{
+		{"", 1, "empty filter"},
+		{"  ", 1, "empty filter"},
+		{"foo:bar", 1, `unknown field "foo"`},
+		{"status:closed", 8, `invalid status "closed"`},
+		{"status open", 8, "expected an operator after status, found open"},
+		{"priority~high", 9, "priority cannot be compared with ~"},
+		{"due<soon", 5, `invalid date "soon"`},
+		{"due<none", 5, "can only be compared with none"},
+		{"id=x", 4, `invalid id "x"`},
+		{"tag:work or", 12, "expected a comparison"},
+		{"and tag:work", 1, "expected a field, found and"},
+		{"status:open and (tag:work", 26, "expected ) to close the ( at column 17"},
+		{"status:open)", 12, "unexpected )"},
+		{`desc~"deploy`, 6, `missing closing "`},
+		{"tag!work", 4, "unexpected '!'"},
+		{"tag:", 5, "expected a value after :"},
+		{"prioridad>=alta", 1, "unknown field"},
+		{"priority>=alta", 11, `invalid priority "alta"`},
+	}
+	for _, tt := range tests {
+		_, err := query.Parse(tt.in)
+		var qerr *query.Error
+		if !errors.As(err, &qerr) || !errors.Is(err, query.ErrInvalidQuery) {
+			t.Errorf("Parse(%q) expected a query error, got %v", tt.in, err)
+			continue
+		}
+		if qerr.Column != tt.column || !strings.Contains(qerr.Msg, tt.msg) {
+			t.Errorf("Parse(%q) = column %d %q, want column %d %q", tt.in, qerr.Column, qerr.Msg, tt.column, tt.msg)
+		}
+	}
+}
+
+func TestError_Caret(t *testing.T) {
+	_, err := query.Parse("due>+7d and tag=")
+	want := "invalid filter at column 17: expected a value after =, found end of the filter\n" +
+		"  due>+7d and tag=\n" +
+		"                  ^"
+	if err == nil || err.Error() != want {
+		t.Errorf("expected\n%s\ngot\n%v", want, err)
+	}
+}
+
+// TestQuery_SQLAndMatch checks that the SQL of the queries and their
+// evaluation in memory keep the same tasks
+func TestQuery_SQLAndMatch(t *testing.T) {
+	ctx := context.Background()
+	database, err := db.ConnectSqlite(":memory:")
+	if err != nil {
+		t.Fatalf("failed to connect to sqlite: %v", err)
+	}
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+	repo := task.NewSqliteRepository(database)
+
+	home := task.Project{Name: "Home"}
+	if err := repo.CreateProject(ctx, &home); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	noon := time.Date(now.Year(), now.Month(), now.Day(), 12, 0, 0, 0, now.Location())
+	day := func(days int) *time.Time {
+		d := noon.AddDate(0, 0, days)
+		return &d
+	}
+	created := time.Date(2026, 3, 1, 9, 0, 0, 0, now.Location())
+	tasks := []task.Task{
+		{Description: "Deploy the API", Priority: task.High, ProjectID: &home.ID, DueAt: day(3), Context: "office",
+			Tags: []task.Tag{{Name: "work"}}, CreatedAt: created},
+		{Description: "Buy milk", Notes: "semi-skimmed", Priority: task.Low, DueAt: day(-1),
+			Tags: []task.Tag{{Name: "home"}}, CreatedAt: created.AddDate(-1, 0, 0)},
+		{Description: "Write report", Priority: task.Medium, Tags: []task.Tag{{Name: "work"}}, CreatedAt: created},
+		{Description: "Old deploy script", Tags: []task.Tag{{Name: "work"}}, CreatedAt: created},
+		{Description: "Call Ana", DueAt: day(10), Context: "phone", CreatedAt: created},
+	}
+	if err := repo.Create(ctx, tasks); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Complete(ctx, []int{3}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.Delete(ctx, []int{4}); err != nil {
+		t.Fatal(err)
+	}
+	all, err := repo.Get(ctx, []int{1, 2, 3, 4, 5}, task.IDs, task.ListOptions{})
+	if err != nil || len(all) != 5 {
+		t.Fatalf("failed to get the tasks: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  []uint
+	}{
+		{`status:open and (tag:work or priority>=high) and due<+7d and created>2026-01-01 and desc~"deploy"`, []uint{1}},
+		{"tag:work", []uint{1, 3}},
+		{"tag:work status:removed", []uint{4}},
+		{"tag!=work", []uint{2, 5}},
+		{"tag:none", []uint{5}},
+		{"not tag:none", []uint{1, 2, 3}},
+		{"tag~OR", []uint{1, 3}},
+		{"project:Home", []uint{1}},
+		{"project!=Home", []uint{2, 3, 5}},
+		{"project:none", []uint{2, 3, 5}},
+		{"project~ho", []uint{1}},
+		{"due<today", []uint{2}},
+		{"not due<today", []uint{1, 3, 5}},
+		{"due:none", []uint{3}},
+		{"due!=none", []uint{1, 2, 5}},
+		{"due>=+3d and due<=+10d", []uint{1, 5}},
+		{"due>+3d", []uint{5}},
+		{"due=+3d", []uint{1}},
+		{"due!=+3d", []uint{2, 3, 5}},
+		{"due<+1w or due:yesterday", []uint{1, 2}},
+		{"created<2026-01-01", []uint{2}},
+		{"created=01/03/2026", []uint{1, 3, 5}},
+		{"priority>medium or context:phone", []uint{1, 5}},
+		{"priority<=low", []uint{2, 5}},
+		{"context:none", []uint{2, 3}},
+		{"notes~SKIMMED", []uint{2}},
+		{"completed:none", []uint{1, 2, 5}},
+		{"completed>=today", []uint{3}},
+		{"status!=open", []uint{3, 4}},
+		{"status:completed or status:removed", []uint{3, 4}},
+		{"id<=2 or id=5", []uint{1, 2, 5}},
+		{`desc="Buy milk"`, []uint{2}},
+		{`desc="buy milk"`, nil},
+		{`desc~"%"`, nil},
+	}
+	for _, tt := range tests {
+		q, err := query.Parse(tt.query)
+		if err != nil {
+			t.Errorf("Parse(%q) unexpected error: %v", tt.query, err)
+			continue
+		}
+		found, err := repo.Get(ctx, nil, task.Matching, task.ListOptions{Where: q})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.query, err)
+			continue
+		}
+		var got, matched []uint
+		for _, task := range found {
+			got = append(got, task.ID)
+		}
+		for _, task := range all {
+			if q.Match(task, now) {
+				matched = append(matched, task.ID)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: SQL got %v, want %v", tt.query, got, tt.want)
+		}
+		if !slices.Equal(matched, tt.want) {
+			t.Errorf("%s: Match got %v, want %v", tt.query, matched, tt.want)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package task
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// Annotation is a timestamped comment appended to a task
+type Annotation struct {
+	ID        uint      `gorm:"primary_key"`
+	TaskID    uint      `gorm:"not null;index"`
+	Text      string    `gorm:"not null"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+}
+
+var ErrEmptyAnnotation = errors.New("annotation cannot be empty")
+
+func (a Annotation) validate() error {
+	if strings.TrimSpace(a.Text) == "" {
+		return ErrEmptyAnnotation
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidDate = errors.New("invalid date")
+
+// dateLayouts are the accepted absolute formats, the ones
+// without time of day are due at the end of that day
+var dateLayouts = []struct {
+	layout  string
+	withDay bool
+}{
+	{"2006-01-02 15:04", true},
+	{"02/01/2006 15:04", true},
+	{"2006-01-02", false},
+	{"02/01/2006", false},
+}
+
+// ParseDate parses a due date relative to now. Apart from the
+// absolute layouts it understands "today" and "tomorrow"
+func ParseDate(s string, now time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "today":
+		return endOfDay(now), nil
+	case "tomorrow":
+		return endOfDay(now.AddDate(0, 0, 1)), nil
+	}
+
+	for _, l := range dateLayouts {
+		t, err := time.ParseInLocation(l.layout, s, now.Location())
+		if err != nil {
+			continue
+		}
+		if !l.withDay {
+			t = endOfDay(t)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%w '%s': use YYYY-MM-DD [HH:MM], DD/MM/YYYY [HH:MM], today or tomorrow", ErrInvalidDate, s)
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func endOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 23, 59, 0, 0, t.Location())
+}
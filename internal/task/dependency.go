@@ -0,0 +1,72 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Dependency means that the task cannot be
+// completed until the blocker is completed
+type Dependency struct {
+	TaskID    uint `gorm:"primaryKey;autoIncrement:false"`
+	BlockerID uint `gorm:"primaryKey;autoIncrement:false;index"`
+}
+
+func (Dependency) TableName() string {
+	return "task_dependencies"
+}
+
+var ErrBlocked = errors.New("task is blocked")
+
+// DependencyCycleError is returned when a new
+// dependency would make a task block itself
+type DependencyCycleError struct {
+	// Path goes from the blocked task through its
+	// blockers until it reaches the same task again
+	Path []int
+}
+
+func (e *DependencyCycleError) Error() string {
+	return fmt.Sprintf("dependency cycle: %s", joinIDs(e.Path, " -> "))
+}
+
+// blockerPath looks for a chain of dependencies going from the
+// task from to the task to, returning the IDs in the chain
+func blockerPath(deps []Dependency, from, to int) []int {
+	blockers := map[int][]int{}
+	for _, d := range deps {
+		blockers[int(d.TaskID)] = append(blockers[int(d.TaskID)], int(d.BlockerID))
+	}
+
+	prev := map[int]int{from: from}
+	pending := []int{from}
+	for len(pending) > 0 {
+		id := pending[0]
+		pending = pending[1:]
+		if id == to {
+			path := []int{id}
+			for id != from {
+				id = prev[id]
+				path = append([]int{id}, path...)
+			}
+			return path
+		}
+		for _, b := range blockers[id] {
+			if _, seen := prev[b]; !seen {
+				prev[b] = id
+				pending = append(pending, b)
+			}
+		}
+	}
+	return nil
+}
+
+func joinIDs(ids []int, sep string) string {
+	ss := make([]string, len(ids))
+	for i, id := range ids {
+		ss[i] = strconv.Itoa(id)
+	}
+	return strings.Join(ss, sep)
+}
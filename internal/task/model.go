@@ -11,22 +11,94 @@ import (
 type Task struct {
 	// We could use gorm.Model that adds to the model
 	// the ID as we have it and the fields CreatedAt, UpdatedAt and DeletedAt
-	ID          uint       `gorm:"primary_key"`
-	Description string     `gorm:"not null"`
+	ID          uint   `gorm:"primary_key"`
+	Description string `gorm:"not null"`
+	// Notes is a free form, possibly multi-line, text
+	Notes     string   `gorm:"not null;default:''"`
+	Priority  Priority `gorm:"not null;default:0;index"`
+	ProjectID *uint    `gorm:"index"`
+	Project   *Project
+	ParentID  *uint      `gorm:"index"`
+	DueAt     *time.Time `gorm:"index"`
+	// Context is where the task can be done, e.g. "phone",
+	// written with a '@' prefix when adding it
+	Context string `gorm:"not null;default:'';index"`
+	// Recurrence is the canonical form of the rule
+	// used to repeat the task when it is completed
+	Recurrence  string     `gorm:"not null;default:''"`
 	CompletedAt *time.Time `sql:"index"`
 	CreatedAt   time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime"`
 	DeletedAt   *time.Time `sql:"index"`
+	Tags        []Tag      `gorm:"many2many:task_tags"`
+	Annotations []Annotation
 }
 
-var ErrEmptyDescription = errors.New("task description cannot be empty")
+var (
+	ErrEmptyDescription = errors.New("task description cannot be empty")
+	ErrTaskNotFound     = errors.New("task not found")
+	ErrTaskRemoved      = errors.New("task is removed")
+)
 
 func (t Task) validate() error {
 	if strings.TrimSpace(t.Description) == "" {
 		return fmt.Errorf("task '%s': %w", t.Description, ErrEmptyDescription)
 	}
+	if !t.Priority.valid() {
+		return fmt.Errorf("task '%s': %w %d", t.Description, ErrInvalidPriority, t.Priority)
+	}
+	if _, err := ParseRecurrence(t.Recurrence); err != nil {
+		return fmt.Errorf("task '%s': %w", t.Description, err)
+	}
+	for _, tag := range t.Tags {
+		if err := validateTag(tag.Name); err != nil {
+			return fmt.Errorf("task '%s': %w", t.Description, err)
+		}
+	}
 	return nil
 }
 
+// next returns the occurrence that follows the task after
+// completing it at completedAt, if the task is recurrent
+func (t Task) next(completedAt time.Time) *Task {
+	r, err := ParseRecurrence(t.Recurrence)
+	if err != nil || r == nil {
+		return nil
+	}
+	from := completedAt
+	if t.DueAt != nil {
+		from = *t.DueAt
+	}
+	due := r.Next(from)
+
+	names := make([]string, len(t.Tags))
+	for i, tag := range t.Tags {
+		names[i] = tag.Name
+	}
+	return &Task{
+		Description: t.Description,
+		Priority:    t.Priority,
+		ProjectID:   t.ProjectID,
+		ParentID:    t.ParentID,
+		DueAt:       &due,
+		Context:     t.Context,
+		Recurrence:  t.Recurrence,
+		Tags:        tagsOf(names),
+	}
+}
+
+// occurrenceOf reports whether t and o are occurrences of
+// the same recurrent task
+func (t Task) occurrenceOf(o Task) bool {
+	return t.Recurrence != "" && t.Recurrence == o.Recurrence && t.Description == o.Description &&
+		(t.ParentID == o.ParentID || t.ParentID != nil && o.ParentID != nil && *t.ParentID == *o.ParentID)
+}
+
+// Overdue reports whether the task is still open after its due date
+func (t Task) Overdue(now time.Time) bool {
+	return t.DueAt != nil && t.CompletedAt == nil && t.DueAt.Before(now)
+}
+
 type ListFilter string
 
 const (
@@ -35,14 +107,98 @@ const (
 	Uncompleted ListFilter = "uncompleted"
 	Completed   ListFilter = "completed"
 	Removed     ListFilter = "removed"
+	// The due filters only take into account the uncompleted tasks
+	Overdue  ListFilter = "overdue"
+	Today    ListFilter = "today"
+	Upcoming ListFilter = "upcoming"
+	// Blocked and Ready are the uncompleted tasks with
+	// and without uncompleted blockers respectively
+	Blocked ListFilter = "blocked"
+	Ready   ListFilter = "ready"
+	// Matching lists the tasks of ListOptions.Where alone,
+	// the removed ones included
+	Matching ListFilter = "matching"
 )
 
-// Maybe in a future we add order by value commands
-/*
+// ListFilters holds the filters that can be chosen by name, all but IDs and Matching
+var ListFilters = []ListFilter{All, Uncompleted, Completed, Removed, Overdue, Today, Upcoming, Blocked, Ready}
+
+// ListOptions narrows and sorts the result of a listing
+// on top of its ListFilter
+type ListOptions struct {
+	// Priorities keeps only the tasks with any of these priorities
+	Priorities []Priority
+	// Tags keeps only the tasks with any of these tags,
+	// or with all of them when AllTags is set
+	Tags    []string
+	AllTags bool
+	// Project keeps only the tasks of the project with that name.
+	// Otherwise the Uncompleted filter hides the archived projects
+	Project string
+	// Order sorts the tasks by that field, ByID when empty.
+	// The tasks without a value for that field go last
+	Order ListOrderValue
+	// Desc reverses the order
+	Desc bool
+	// Where keeps only the tasks meeting the condition
+	Where Condition
+}
+
+// Condition is a filter expression, see package query. The
+// SqliteRepository adds its SQL to the query while the other
+// backends can evaluate it in memory
+type Condition interface {
+	// SQL returns the condition on the tasks table and its arguments
+	SQL(now time.Time) (string, []any)
+	// Match reports whether the task, with its Project and Tags, meets it
+	Match(t Task, now time.Time) bool
+}
+
+// CreateOptions holds the fields shared by all
+// the tasks created in a single call
+type CreateOptions struct {
+	Priority Priority
+	DueAt    *time.Time
+	// Tags are added to the ones written
+	// in the description with a '+' prefix
+	Tags []string
+	// Project is the name of the project of the tasks, if any
+	Project string
+	// Parent is the ID of the task the new ones are subtasks of, if any
+	Parent int
+	// Recurrence is the rule to repeat the tasks, see ParseRecurrence
+	Recurrence string
+	Notes      string
+	// Raw keeps the descriptions as written, otherwise the priority,
+	// due date, tags, context and project are taken out of them,
+	// see ParseQuickAdd. The ones found there win over the options
+	Raw bool
+}
+
+// Changes holds every editable field of a task, see Service.Change
+type Changes struct {
+	Description string
+	Notes       string
+	Priority    Priority
+	DueAt       *time.Time
+	Tags        []string
+	// Project is the name of the project of the task, none when empty
+	Project string
+	// Recurrence is the rule to repeat the task, see ParseRecurrence
+	Recurrence string
+}
+
 type ListOrderValue string
 
 const (
-	CreatedAt   ListOrderValue = "created"
-	DeletedAt   ListOrderValue = "deleted"
-	CompletedAt ListOrderValue = "completed"
-)*/
+	ByID        ListOrderValue = "id"
+	ByCreated   ListOrderValue = "created"
+	ByCompleted ListOrderValue = "completed"
+	ByDeleted   ListOrderValue = "deleted"
+	ByDue       ListOrderValue = "due"
+	// ByPriority lists the most important tasks first
+	ByPriority ListOrderValue = "priority"
+)
+
+// ListOrderValues holds every valid ListOrderValue
+var ListOrderValues = []ListOrderValue{ByID, ByCreated, ByCompleted, ByDeleted, ByDue, ByPriority}
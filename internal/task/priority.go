@@ -0,0 +1,51 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Priority is stored as an integer so the tasks
+// can be sorted by importance directly in the database
+type Priority int
+
+const (
+	NoPriority Priority = iota
+	Low
+	Medium
+	High
+)
+
+var ErrInvalidPriority = errors.New("invalid priority")
+
+var priorityNames = map[Priority]string{
+	NoPriority: "none",
+	Low:        "low",
+	Medium:     "medium",
+	High:       "high",
+}
+
+func (p Priority) String() string {
+	if name, ok := priorityNames[p]; ok {
+		return name
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+func (p Priority) valid() bool {
+	_, ok := priorityNames[p]
+	return ok
+}
+
+// ParsePriority accepts the priority names (none, low, medium, high)
+// or their first letter, ignoring the case
+func ParsePriority(s string) (Priority, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	for p, name := range priorityNames {
+		if s == name || (s != "" && s == name[:1]) {
+			return p, nil
+		}
+	}
+	return NoPriority, fmt.Errorf("%w '%s': must be none, low, medium or high", ErrInvalidPriority, s)
+}
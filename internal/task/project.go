@@ -0,0 +1,32 @@
+package task
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+type Project struct {
+	ID         uint       `gorm:"primary_key"`
+	Name       string     `gorm:"not null;uniqueIndex"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime"`
+	ArchivedAt *time.Time `gorm:"index"`
+}
+
+var (
+	ErrEmptyProjectName = errors.New("project name cannot be empty")
+	ErrProjectNotFound  = errors.New("project not found")
+	ErrProjectExists    = errors.New("project already exists")
+	ErrProjectArchived  = errors.New("project is archived")
+)
+
+func (p Project) validate() error {
+	if strings.TrimSpace(p.Name) == "" {
+		return ErrEmptyProjectName
+	}
+	return nil
+}
+
+func (p Project) Archived() bool {
+	return p.ArchivedAt != nil
+}
@@ -0,0 +1,253 @@
+package task
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// The prefixes of the words that set a field of the task in a quick add
+const (
+	priorityPrefix = "!"
+	contextPrefix  = "@"
+	projectPrefix  = "#"
+)
+
+// QuickAdd holds the fields ParseQuickAdd finds in the text of a task
+type QuickAdd struct {
+	Description string
+	// Priority is nil when the text does not set it
+	Priority *Priority
+	DueAt    *time.Time
+	Tags     []string
+	Context  string
+	Project  string
+}
+
+// ParseQuickAdd takes out of text the words that set the fields of a
+// task, the remaining ones are the description. It understands:
+//
+//   - !high, !medium, !low, !none or their first letter: the priority
+//   - +name: a tag
+//   - @name: the context
+//   - #name: the project, the name starts with a letter so "#12" is kept
+//   - a date: today, tomorrow, a weekday, next <weekday>, in N days,
+//     weeks or months, eow, eom, YYYY-MM-DD or DD/MM/YYYY, optionally
+//     after "on", "by" or "due"
+//   - a time of day: 3pm, 3:30pm, 3 pm or 15:30, optionally after "at"
+//
+// Only the first date and time are taken. The dates are relative to now,
+// a date without time is due at the end of the day and a time without
+// date is due today. "next friday" is the Friday of the next week, the
+// weeks starting on Monday
+func ParseQuickAdd(text string, now time.Time) QuickAdd {
+	var q QuickAdd
+	var date *time.Time
+	hour, minute := -1, 0
+	words := strings.Fields(text)
+	var rest []string
+	for i := 0; i < len(words); {
+		if date == nil {
+			if d, n := matchDate(words[i:], now); n > 0 {
+				date = &d
+				i += n
+				continue
+			}
+		}
+		if hour < 0 {
+			if h, m, n := matchTime(words[i:]); n > 0 {
+				hour, minute = h, m
+				i += n
+				continue
+			}
+		}
+		if !q.field(words[i]) {
+			rest = append(rest, words[i])
+		}
+		i++
+	}
+
+	switch {
+	case hour >= 0:
+		day := now
+		if date != nil {
+			day = *date
+		}
+		y, m, d := day.Date()
+		due := time.Date(y, m, d, hour, minute, 0, 0, now.Location())
+		q.DueAt = &due
+	case date != nil:
+		q.DueAt = date
+	}
+
+	q.Description = text
+	if len(rest) < len(words) {
+		q.Description = strings.Join(rest, " ")
+	}
+	return q
+}
+
+// field sets the field of q written by the prefixed word w, if any
+func (q *QuickAdd) field(w string) bool {
+	if len(w) < 2 {
+		return false
+	}
+	switch name := w[1:]; {
+	case strings.HasPrefix(w, priorityPrefix):
+		p, err := ParsePriority(name)
+		if err != nil {
+			return false
+		}
+		q.Priority = &p
+	case strings.HasPrefix(w, tagPrefix):
+		q.Tags = append(q.Tags, name)
+	case strings.HasPrefix(w, contextPrefix):
+		q.Context = strings.ToLower(name)
+	case strings.HasPrefix(w, projectPrefix):
+		r := []rune(name)
+		if !unicode.IsLetter(r[0]) {
+			return false
+		}
+		q.Project = name
+	default:
+		return false
+	}
+	return true
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// matchDate parses the date at the start of words,
+// returning the number of words it takes or 0
+func matchDate(words []string, now time.Time) (time.Time, int) {
+	if len(words) == 0 {
+		return time.Time{}, 0
+	}
+	w := strings.ToLower(words[0])
+	switch w {
+	case "on", "by", "due":
+		if d, n := matchDate(words[1:], now); n > 0 {
+			return d, n + 1
+		}
+		return time.Time{}, 0
+	case "today":
+		return endOfDay(now), 1
+	case "tomorrow":
+		return endOfDay(now.AddDate(0, 0, 1)), 1
+	case "eow":
+		return endOfDay(now.AddDate(0, 0, 7-isoWeekday(now.Weekday()))), 1
+	case "eom":
+		y, m, _ := now.Date()
+		return endOfDay(time.Date(y, m+1, 0, 0, 0, 0, 0, now.Location())), 1
+	case "next":
+		if len(words) < 2 {
+			return time.Time{}, 0
+		}
+		wd, ok := weekdays[strings.ToLower(words[1])]
+		if !ok {
+			return time.Time{}, 0
+		}
+		days := 7 - isoWeekday(now.Weekday()) + isoWeekday(wd)
+		return endOfDay(now.AddDate(0, 0, days)), 2
+	case "in":
+		if len(words) < 3 {
+			return time.Time{}, 0
+		}
+		n, err := strconv.Atoi(words[1])
+		if words[1] == "a" || words[1] == "an" {
+			n, err = 1, nil
+		}
+		if err != nil || n < 0 {
+			return time.Time{}, 0
+		}
+		switch strings.TrimSuffix(strings.ToLower(words[2]), "s") {
+		case "day":
+			return endOfDay(now.AddDate(0, 0, n)), 3
+		case "week":
+			return endOfDay(now.AddDate(0, 0, 7*n)), 3
+		case "month":
+			return endOfDay(now.AddDate(0, n, 0)), 3
+		}
+		return time.Time{}, 0
+	}
+	if wd, ok := weekdays[w]; ok {
+		// the next one, a week later when it is today
+		days := (int(wd)-int(now.Weekday())+6)%7 + 1
+		return endOfDay(now.AddDate(0, 0, days)), 1
+	}
+	if strings.ContainsAny(w, "-/") {
+		if d, err := ParseDate(w, now); err == nil {
+			return d, 1
+		}
+	}
+	return time.Time{}, 0
+}
+
+// matchTime parses the time of day at the start of words,
+// returning the number of words it takes or 0
+func matchTime(words []string) (hour, minute, n int) {
+	if len(words) == 0 {
+		return 0, 0, 0
+	}
+	w := strings.ToLower(words[0])
+	if w == "at" {
+		if h, m, n := matchTime(words[1:]); n > 0 {
+			return h, m, n + 1
+		}
+		return 0, 0, 0
+	}
+	n = 1
+	suffix := ""
+	if s := w[max(0, len(w)-2):]; s == "am" || s == "pm" {
+		w, suffix = strings.TrimSuffix(w, s), s
+	} else if len(words) > 1 && (strings.EqualFold(words[1], "am") || strings.EqualFold(words[1], "pm")) {
+		suffix, n = strings.ToLower(words[1]), 2
+	}
+
+	h, m, hasMinutes := strings.Cut(w, ":")
+	if !digits(h, 1, 2) || hasMinutes && !digits(m, 2, 2) {
+		return 0, 0, 0
+	}
+	hour, _ = strconv.Atoi(h)
+	if hasMinutes {
+		if minute, _ = strconv.Atoi(m); minute > 59 {
+			return 0, 0, 0
+		}
+	}
+	switch {
+	case suffix == "" && hasMinutes && hour <= 23:
+	case suffix != "" && hour >= 1 && hour <= 12:
+		hour %= 12
+		if suffix == "pm" {
+			hour += 12
+		}
+	default:
+		return 0, 0, 0
+	}
+	return hour, minute, n
+}
+
+// digits reports whether s has between min and max digits and nothing else
+func digits(s string, min, max int) bool {
+	if len(s) < min || len(s) > max {
+		return false
+	}
+	return strings.IndexFunc(s, func(r rune) bool { return r < '0' || r > '9' }) < 0
+}
+
+// isoWeekday numbers the days from Monday, 1, to Sunday, 7
+func isoWeekday(d time.Weekday) int {
+	if d == time.Sunday {
+		return 7
+	}
+	return int(d)
+}
@@ -0,0 +1,63 @@
+package task_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"arcedo/cli-todo/internal/task"
+)
+
+func TestParseQuickAdd(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC)
+	day := func(m time.Month, d, hour, min int) *time.Time {
+		t := time.Date(2026, m, d, hour, min, 0, 0, time.UTC)
+		return &t
+	}
+	high, low := task.High, task.Low
+
+	cases := []struct {
+		in   string
+		want task.QuickAdd
+	}{
+		{"Call Bob tomorrow 3pm !high +work @phone #projectX", task.QuickAdd{
+			Description: "Call Bob", Priority: &high, DueAt: day(10, 22, 15, 0),
+			Tags: []string{"work"}, Context: "phone", Project: "projectX",
+		}},
+		{"Buy milk", task.QuickAdd{Description: "Buy milk"}},
+		{"  keep   the spacing  ", task.QuickAdd{Description: "  keep   the spacing  "}},
+		{"Review PR friday", task.QuickAdd{Description: "Review PR", DueAt: day(10, 23, 23, 59)}},
+		{"Review PR wednesday", task.QuickAdd{Description: "Review PR", DueAt: day(10, 28, 23, 59)}},
+		{"Plan next friday", task.QuickAdd{Description: "Plan", DueAt: day(10, 30, 23, 59)}},
+		{"Plan next Monday", task.QuickAdd{Description: "Plan", DueAt: day(10, 26, 23, 59)}},
+		{"Renew in 3 days", task.QuickAdd{Description: "Renew", DueAt: day(10, 24, 23, 59)}},
+		{"Renew in 2 weeks", task.QuickAdd{Description: "Renew", DueAt: day(11, 4, 23, 59)}},
+		{"Renew in a month", task.QuickAdd{Description: "Renew", DueAt: day(11, 21, 23, 59)}},
+		{"Report eom", task.QuickAdd{Description: "Report", DueAt: day(10, 31, 23, 59)}},
+		{"Report eow", task.QuickAdd{Description: "Report", DueAt: day(10, 25, 23, 59)}},
+		{"Pay rent by 2026-11-01", task.QuickAdd{Description: "Pay rent", DueAt: day(11, 1, 23, 59)}},
+		{"Standup at 9:30am", task.QuickAdd{Description: "Standup", DueAt: day(10, 21, 9, 30)}},
+		{"Standup at 15:30 today", task.QuickAdd{Description: "Standup", DueAt: day(10, 21, 15, 30)}},
+		{"Lunch 12 pm on friday", task.QuickAdd{Description: "Lunch", DueAt: day(10, 23, 12, 0)}},
+		{"Midnight run 12am tomorrow", task.QuickAdd{Description: "Midnight run", DueAt: day(10, 22, 0, 0)}},
+		{"Fix #12 !l", task.QuickAdd{Description: "Fix #12", Priority: &low}},
+		{"Read in the park !important", task.QuickAdd{Description: "Read in the park !important"}},
+		{"Email bob@example.com @Office", task.QuickAdd{Description: "Email bob@example.com", Context: "office"}},
+		{"Buy 3 apples", task.QuickAdd{Description: "Buy 3 apples"}},
+		{"Meet tomorrow about friday", task.QuickAdd{Description: "Meet about friday", DueAt: day(10, 22, 23, 59)}},
+	}
+	for _, c := range cases {
+		got := task.ParseQuickAdd(c.in, now)
+		if got.Description != c.want.Description || got.Context != c.want.Context ||
+			got.Project != c.want.Project || !slices.Equal(got.Tags, c.want.Tags) {
+			t.Errorf("ParseQuickAdd(%q) = %+v; want %+v", c.in, got, c.want)
+		}
+		if (got.Priority == nil) != (c.want.Priority == nil) || got.Priority != nil && *got.Priority != *c.want.Priority {
+			t.Errorf("ParseQuickAdd(%q) priority = %v; want %v", c.in, got.Priority, c.want.Priority)
+		}
+		if (got.DueAt == nil) != (c.want.DueAt == nil) || got.DueAt != nil && !got.DueAt.Equal(*c.want.DueAt) {
+			t.Errorf("ParseQuickAdd(%q) due = %v; want %v", c.in, got.DueAt, c.want.DueAt)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence is the rule used to create the next occurrence of
+// a task when it is completed. It repeats every Interval units,
+// or on the given Weekdays when there are any
+type Recurrence struct {
+	Unit     RecurrenceUnit
+	Interval int
+	Weekdays []time.Weekday
+}
+
+type RecurrenceUnit string
+
+const (
+	Days   RecurrenceUnit = "day"
+	Weeks  RecurrenceUnit = "week"
+	Months RecurrenceUnit = "month"
+	Years  RecurrenceUnit = "year"
+)
+
+var ErrInvalidRecurrence = errors.New("invalid recurrence")
+
+var unitAliases = map[string]RecurrenceUnit{
+	"daily":   Days,
+	"weekly":  Weeks,
+	"monthly": Months,
+	"yearly":  Years,
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseRecurrence understands the rules daily, weekly, monthly, yearly,
+// "every N days|weeks|months|years", weekdays and comma separated
+// weekdays such as "mon,thu" or "monday,thursday". An empty rule means no recurrence
+func ParseRecurrence(s string) (*Recurrence, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return nil, nil
+	}
+	if unit, ok := unitAliases[s]; ok {
+		return &Recurrence{Unit: unit, Interval: 1}, nil
+	}
+	if s == "weekdays" {
+		return &Recurrence{Unit: Days, Interval: 1, Weekdays: []time.Weekday{
+			time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday,
+		}}, nil
+	}
+
+	if rest, ok := strings.CutPrefix(s, "every "); ok {
+		fields := strings.Fields(rest)
+		n := 1
+		if len(fields) == 2 {
+			var err error
+			if n, err = strconv.Atoi(fields[0]); err != nil || n < 1 {
+				return nil, fmt.Errorf("%w '%s': the interval must be a positive number", ErrInvalidRecurrence, s)
+			}
+			fields = fields[1:]
+		}
+		if len(fields) == 1 {
+			unit := RecurrenceUnit(strings.TrimSuffix(fields[0], "s"))
+			if slices.Contains([]RecurrenceUnit{Days, Weeks, Months, Years}, unit) {
+				return &Recurrence{Unit: unit, Interval: n}, nil
+			}
+		}
+		if n == 1 && len(fields) == 1 {
+			s = fields[0]
+		}
+	}
+
+	var days []time.Weekday
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if len(name) < 3 {
+			return nil, fmt.Errorf("%w '%s'", ErrInvalidRecurrence, s)
+		}
+		// the abbreviation or the full name, not any word starting like one
+		day, ok := weekdayNames[name[:3]]
+		if !ok || len(name) > 3 && name != strings.ToLower(day.String()) {
+			return nil, fmt.Errorf("%w '%s'", ErrInvalidRecurrence, s)
+		}
+		if !slices.Contains(days, day) {
+			days = append(days, day)
+		}
+	}
+	slices.Sort(days)
+	return &Recurrence{Unit: Days, Interval: 1, Weekdays: days}, nil
+}
+
+// String returns the canonical form of the rule, which
+// is the one stored and parsed back by ParseRecurrence
+func (r Recurrence) String() string {
+	if len(r.Weekdays) > 0 {
+		names := make([]string, len(r.Weekdays))
+		for i, d := range r.Weekdays {
+			names[i] = strings.ToLower(d.String()[:3])
+		}
+		return strings.Join(names, ",")
+	}
+	if r.Interval == 1 {
+		for alias, unit := range unitAliases {
+			if unit == r.Unit {
+				return alias
+			}
+		}
+	}
+	return fmt.Sprintf("every %d %ss", r.Interval, r.Unit)
+}
+
+// Next returns the first occurrence after from,
+// keeping its time of day
+func (r Recurrence) Next(from time.Time) time.Time {
+	if len(r.Weekdays) > 0 {
+		next := from.AddDate(0, 0, 1)
+		for !slices.Contains(r.Weekdays, next.Weekday()) {
+			next = next.AddDate(0, 0, 1)
+		}
+		return next
+	}
+
+	switch r.Unit {
+	case Weeks:
+		return from.AddDate(0, 0, 7*r.Interval)
+	case Months:
+		return addMonths(from, r.Interval)
+	case Years:
+		return addMonths(from, 12*r.Interval)
+	default:
+		return from.AddDate(0, 0, r.Interval)
+	}
+}
+
+// addMonths keeps the day of the month, or uses the last day of
+// the month when it is shorter, so Jan 31 is followed by Feb 28
+func addMonths(t time.Time, n int) time.Time {
+	y, m, d := t.Date()
+	first := time.Date(y, m+time.Month(n), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+	last := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(d, last)-1)
+}
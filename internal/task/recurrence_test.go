@@ -0,0 +1,86 @@
+package task_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"arcedo/cli-todo/internal/task"
+)
+
+func TestParseRecurrence(t *testing.T) {
+	cases := map[string]string{
+		"daily":          "daily",
+		"Weekly":         "weekly",
+		"monthly":        "monthly",
+		"yearly":         "yearly",
+		"every day":      "daily",
+		"every 3 days":   "every 3 days",
+		"every 2 weeks":  "every 2 weeks",
+		"every 1 month":  "monthly",
+		"every monday":   "mon",
+		"thu,mon":        "mon,thu",
+		"Monday, friday": "mon,fri",
+		"weekdays":       "mon,tue,wed,thu,fri",
+	}
+	for in, want := range cases {
+		r, err := task.ParseRecurrence(in)
+		if err != nil {
+			t.Errorf("ParseRecurrence(%q) unexpected error: %v", in, err)
+			continue
+		}
+		if got := r.String(); got != want {
+			t.Errorf("ParseRecurrence(%q) = %q; want %q", in, got, want)
+		}
+		again, err := task.ParseRecurrence(r.String())
+		if err != nil || again.String() != want {
+			t.Errorf("canonical form %q does not parse back: %v, %v", want, again, err)
+		}
+	}
+
+	if r, err := task.ParseRecurrence(""); r != nil || err != nil {
+		t.Errorf("expected no recurrence for an empty rule, got %v, %v", r, err)
+	}
+	for _, in := range []string{"sometimes", "every 0 days", "every -1 weeks", "every 2 mon", "mo", "monkey,thunder", "every monkey", "mond", "fridays"} {
+		if _, err := task.ParseRecurrence(in); !errors.Is(err, task.ErrInvalidRecurrence) {
+			t.Errorf("ParseRecurrence(%q): expected ErrInvalidRecurrence, got %v", in, err)
+		}
+	}
+}
+
+func TestRecurrence_Next(t *testing.T) {
+	// Thursday
+	from := time.Date(2026, 1, 29, 9, 0, 0, 0, time.UTC)
+	cases := []struct {
+		rule string
+		want time.Time
+	}{
+		{"daily", time.Date(2026, 1, 30, 9, 0, 0, 0, time.UTC)},
+		{"every 3 days", time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC)},
+		{"weekly", time.Date(2026, 2, 5, 9, 0, 0, 0, time.UTC)},
+		{"every 2 weeks", time.Date(2026, 2, 12, 9, 0, 0, 0, time.UTC)},
+		{"monthly", time.Date(2026, 2, 28, 9, 0, 0, 0, time.UTC)},
+		{"every 2 months", time.Date(2026, 3, 29, 9, 0, 0, 0, time.UTC)},
+		{"yearly", time.Date(2027, 1, 29, 9, 0, 0, 0, time.UTC)},
+		{"mon,thu", time.Date(2026, 2, 2, 9, 0, 0, 0, time.UTC)},
+		{"thu", time.Date(2026, 2, 5, 9, 0, 0, 0, time.UTC)},
+		{"weekdays", time.Date(2026, 1, 30, 9, 0, 0, 0, time.UTC)},
+	}
+	for _, tc := range cases {
+		r, err := task.ParseRecurrence(tc.rule)
+		if err != nil {
+			t.Fatalf("ParseRecurrence(%q) unexpected error: %v", tc.rule, err)
+		}
+		if got := r.Next(from); !got.Equal(tc.want) {
+			t.Errorf("%s: Next(%v) = %v; want %v", tc.rule, from, got, tc.want)
+		}
+	}
+
+	t.Run("month end is kept", func(t *testing.T) {
+		r, _ := task.ParseRecurrence("monthly")
+		leap := time.Date(2028, 1, 31, 0, 0, 0, 0, time.UTC)
+		if got := r.Next(leap); !got.Equal(time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("expected Feb 29, got %v", got)
+		}
+	})
+}
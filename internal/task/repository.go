@@ -7,6 +7,24 @@ import (
 type Repository interface {
 	Create(ctx context.Context, tasks []Task) error
 	Delete(ctx context.Context, ids []int) (int, error)
-	Get(ctx context.Context, ids []int, filter ListFilter) ([]Task, error)
+	Get(ctx context.Context, ids []int, filter ListFilter, opts ListOptions) ([]Task, error)
+	Search(ctx context.Context, query SearchQuery, filter ListFilter) ([]Task, error)
 	Complete(ctx context.Context, ids []int) (int, error)
+	Uncomplete(ctx context.Context, ids []int) (int, error)
+	Update(ctx context.Context, task Task) error
+	Restore(ctx context.Context, ids []int) (int, error)
+	Purge(ctx context.Context, ids []int) (int, error)
+	Descendants(ctx context.Context, ids []int) ([]Task, error)
+	AddDependencies(ctx context.Context, id int, blockers []int) error
+	RemoveDependencies(ctx context.Context, id int, blockers []int) (int, error)
+	Dependencies(ctx context.Context) ([]Dependency, error)
+	AddTags(ctx context.Context, id int, tags []string) error
+	RemoveTags(ctx context.Context, id int, tags []string) error
+	Tags(ctx context.Context) ([]TagCount, error)
+	AddAnnotation(ctx context.Context, annotation *Annotation) error
+	CreateProject(ctx context.Context, project *Project) error
+	GetProject(ctx context.Context, name string) (Project, error)
+	GetProjects(ctx context.Context) ([]Project, error)
+	UpdateProject(ctx context.Context, project Project) error
+	AssignProject(ctx context.Context, ids []int, projectID *uint) (int, error)
 }
@@ -0,0 +1,392 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// SearchTable is the FTS5 table db.Migrate keeps in sync with the
+// tasks through triggers. When sqlite is built without FTS5 the
+// triggers are missing and the search falls back to a LIKE scan
+const SearchTable = "tasks_fts"
+
+var ErrInvalidSearch = errors.New("invalid search")
+
+// SearchQuery is a parsed full-text search. The words match whole words
+// of the description or the notes, ignoring the case:
+//
+//   - milk: the word
+//   - mil*: the words starting with "mil"
+//   - "buy milk": the phrase, the words one after the other
+//   - a b, a AND b: both, a OR b: either, a NOT b: a but not b
+//   - parentheses group, AND binds tighter than OR
+type SearchQuery struct {
+	root searchNode
+	text string
+}
+
+func (q SearchQuery) String() string {
+	return q.text
+}
+
+// searchNode is a node of the syntax tree of a search, either a
+// searchTerm or a searchOp
+type searchNode interface {
+	// fts writes the node as an FTS5 query
+	fts(b *strings.Builder)
+}
+
+// searchTerm is a word or phrase, the words are lowercased tokens
+type searchTerm struct {
+	words  []string
+	prefix bool
+}
+
+// searchOp joins two nodes with AND, OR or NOT
+type searchOp struct {
+	op          string
+	left, right searchNode
+}
+
+func (t searchTerm) fts(b *strings.Builder) {
+	b.WriteString(`"` + strings.Join(t.words, " ") + `"`)
+	if t.prefix {
+		b.WriteString(" *")
+	}
+}
+
+func (o searchOp) fts(b *strings.Builder) {
+	b.WriteString("(")
+	o.left.fts(b)
+	b.WriteString(" " + o.op + " ")
+	o.right.fts(b)
+	b.WriteString(")")
+}
+
+// FTS returns the query in the FTS5 syntax, with every word quoted
+// so the punctuation in them cannot be read as an operator
+func (q SearchQuery) FTS() string {
+	var b strings.Builder
+	q.root.fts(&b)
+	return b.String()
+}
+
+// ParseSearch parses a search, see SearchQuery
+func ParseSearch(s string) (SearchQuery, error) {
+	p := searchParser{tokens: lexSearch(s)}
+	if len(p.tokens) == 0 {
+		return SearchQuery{}, fmt.Errorf("%w: nothing to search", ErrInvalidSearch)
+	}
+	root, err := p.or()
+	if err == nil && p.pos < len(p.tokens) {
+		err = fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	if err != nil {
+		return SearchQuery{}, fmt.Errorf("%w '%s': %w", ErrInvalidSearch, s, err)
+	}
+	return SearchQuery{root: root, text: s}, nil
+}
+
+// lexSearch splits a search in words, quoted phrases and parentheses.
+// The phrases keep their quotes and a following '*'
+func lexSearch(s string) []string {
+	var tokens []string
+	r := []rune(s)
+	for i := 0; i < len(r); {
+		switch c := r[i]; {
+		case unicode.IsSpace(c):
+			i++
+		case c == '(' || c == ')':
+			tokens = append(tokens, string(c))
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(r) && r[end] != '"' {
+				end++
+			}
+			end = min(end+1, len(r))
+			if end < len(r) && r[end] == '*' {
+				end++
+			}
+			tokens = append(tokens, string(r[i:end]))
+			i = end
+		default:
+			end := i
+			for end < len(r) && !unicode.IsSpace(r[end]) && !strings.ContainsRune(`()"`, r[end]) {
+				end++
+			}
+			tokens = append(tokens, string(r[i:end]))
+			i = end
+		}
+	}
+	return tokens
+}
+
+type searchParser struct {
+	tokens []string
+	pos    int
+}
+
+func (p *searchParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+// or := and ("OR" and)*
+func (p *searchParser) or() (searchNode, error) {
+	left, err := p.and()
+	for err == nil && p.peek() == "OR" {
+		p.pos++
+		var right searchNode
+		if right, err = p.and(); err == nil {
+			left = searchOp{"OR", left, right}
+		}
+	}
+	return left, err
+}
+
+// and := term (["AND"] term | "NOT" term)*
+func (p *searchParser) and() (searchNode, error) {
+	left, err := p.term()
+	for err == nil {
+		op := "AND"
+		switch p.peek() {
+		case "", ")", "OR":
+			return left, nil
+		case "AND", "NOT":
+			op = p.peek()
+			p.pos++
+		}
+		var right searchNode
+		if right, err = p.term(); err == nil {
+			left = searchOp{op, left, right}
+		}
+	}
+	return nil, err
+}
+
+// term := word | word* | "phrase" | "phrase"* | "(" or ")"
+func (p *searchParser) term() (searchNode, error) {
+	tok := p.peek()
+	p.pos++
+	switch tok {
+	case "":
+		return nil, errors.New("missing a word at the end")
+	case "AND", "OR", "NOT":
+		return nil, fmt.Errorf("missing a word before %s", tok)
+	case ")":
+		return nil, errors.New("unexpected )")
+	case "(":
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, errors.New("missing )")
+		}
+		p.pos++
+		return node, nil
+	}
+
+	t := searchTerm{}
+	tok, t.prefix = strings.CutSuffix(tok, "*")
+	if strings.HasPrefix(tok, `"`) {
+		if len(tok) < 2 || !strings.HasSuffix(tok, `"`) {
+			return nil, errors.New(`missing closing "`)
+		}
+		tok = tok[1 : len(tok)-1]
+	}
+	for _, w := range searchWords(tok) {
+		t.words = append(t.words, w.text)
+	}
+	if len(t.words) == 0 {
+		return nil, fmt.Errorf("%q has no letters nor digits", tok)
+	}
+	return t, nil
+}
+
+// searchWord is a word of a text at [start, end)
+type searchWord struct {
+	text       string
+	start, end int
+}
+
+// searchWords splits s in lowercased words of letters and digits,
+// the same way the full-text index does
+func searchWords(s string) []searchWord {
+	var words []searchWord
+	start := -1
+	for i, r := range s + " " {
+		inWord := unicode.IsLetter(r) || unicode.IsNumber(r)
+		switch {
+		case inWord && start < 0:
+			start = i
+		case !inWord && start >= 0:
+			words = append(words, searchWord{strings.ToLower(s[start:i]), start, i})
+			start = -1
+		}
+	}
+	return words
+}
+
+// matches returns where t is found in words, as the
+// indexes of its first and past its last word
+func (t searchTerm) matches(words []searchWord) [][2]int {
+	var found [][2]int
+	for i := 0; i+len(t.words) <= len(words); i++ {
+		ok := true
+		for j, w := range t.words {
+			last := j == len(t.words)-1
+			if words[i+j].text != w && !(last && t.prefix && strings.HasPrefix(words[i+j].text, w)) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			found = append(found, [2]int{i, i + len(t.words)})
+		}
+	}
+	return found
+}
+
+// Score reports whether the fields match the query, with the number of
+// matches of its terms. The matches in the first field count double,
+// it is how the tasks are ranked without FTS5
+func (q SearchQuery) Score(fields ...string) (int, bool) {
+	words := make([][]searchWord, len(fields))
+	for i, f := range fields {
+		words[i] = searchWords(f)
+	}
+	return score(q.root, words)
+}
+
+func score(n searchNode, fields [][]searchWord) (int, bool) {
+	switch n := n.(type) {
+	case searchTerm:
+		total := 0
+		for i, words := range fields {
+			weight := 1
+			if i == 0 {
+				weight = 2
+			}
+			total += weight * len(n.matches(words))
+		}
+		return total, total > 0
+	case searchOp:
+		left, lok := score(n.left, fields)
+		right, rok := score(n.right, fields)
+		switch n.op {
+		case "AND":
+			return left + right, lok && rok
+		case "OR":
+			return left + right, lok || rok
+		default:
+			return left, lok && !rok
+		}
+	}
+	return 0, false
+}
+
+// Highlight returns s with mark applied to the words matched by the
+// query, the ones only excluded by NOT are left as they are
+func (q SearchQuery) Highlight(s string, mark func(string) string) string {
+	words := searchWords(s)
+	marked := q.marked(words)
+	var b strings.Builder
+	last := 0
+	for i := 0; i < len(words); i++ {
+		if !marked[i] {
+			continue
+		}
+		// the consecutive words are marked together
+		j := i
+		for j+1 < len(words) && marked[j+1] {
+			j++
+		}
+		b.WriteString(s[last:words[i].start])
+		b.WriteString(mark(s[words[i].start:words[j].end]))
+		last, i = words[j].end, j
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// Found reports whether s has any word Highlight would mark
+func (q SearchQuery) Found(s string) bool {
+	return slices.Contains(q.marked(searchWords(s)), true)
+}
+
+// marked tells which words are matched by the terms
+// of the query that are not excluded by NOT
+func (q SearchQuery) marked(words []searchWord) []bool {
+	marked := make([]bool, len(words))
+	var walk func(n searchNode)
+	walk = func(n searchNode) {
+		switch n := n.(type) {
+		case searchTerm:
+			for _, m := range n.matches(words) {
+				for i := m[0]; i < m[1]; i++ {
+					marked[i] = true
+				}
+			}
+		case searchOp:
+			walk(n.left)
+			if n.op != "NOT" {
+				walk(n.right)
+			}
+		}
+	}
+	walk(q.root)
+	return marked
+}
+
+// likes returns the SQL condition, and its arguments, that keeps at
+// least the tasks matching the query with LIKE, so only those are
+// scored. Only the ASCII words are compared, as LIKE ignores the case
+// of ASCII letters only
+func (q SearchQuery) likes() (string, []any) {
+	return likes(q.root)
+}
+
+func likes(n searchNode) (string, []any) {
+	switch n := n.(type) {
+	case searchTerm:
+		var conds []string
+		var args []any
+		for _, w := range n.words {
+			if !isASCII(w) {
+				continue
+			}
+			pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(w) + "%"
+			conds = append(conds, `(description LIKE ? ESCAPE '\' OR notes LIKE ? ESCAPE '\')`)
+			args = append(args, pattern, pattern)
+		}
+		if len(conds) == 0 {
+			return "1 = 1", nil
+		}
+		return strings.Join(conds, " AND "), args
+	case searchOp:
+		left, largs := likes(n.left)
+		switch n.op {
+		case "NOT":
+			// the tasks with the excluded words are dropped when scored
+			return left, largs
+		case "AND":
+			right, rargs := likes(n.right)
+			return "(" + left + ") AND (" + right + ")", append(largs, rargs...)
+		default:
+			right, rargs := likes(n.right)
+			return "(" + left + ") OR (" + right + ")", append(largs, rargs...)
+		}
+	}
+	return "1 = 1", nil
+}
+
+func isASCII(s string) bool {
+	return strings.IndexFunc(s, func(r rune) bool { return r > unicode.MaxASCII }) < 0
+}
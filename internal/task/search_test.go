@@ -0,0 +1,56 @@
+package task_test
+
+import (
+	"errors"
+	"testing"
+
+	"arcedo/cli-todo/internal/task"
+)
+
+func TestParseSearch(t *testing.T) {
+	cases := map[string]string{
+		"milk":                   `"milk"`,
+		"Milk*":                  `"milk" *`,
+		`"buy  fresh Milk"`:      `"buy fresh milk"`,
+		`"buy mil"*`:             `"buy mil" *`,
+		"buy milk":               `("buy" AND "milk")`,
+		"buy OR sell milk":       `("buy" OR ("sell" AND "milk"))`,
+		"buy NOT milk":           `("buy" NOT "milk")`,
+		"(buy OR sell) AND milk": `(("buy" OR "sell") AND "milk")`,
+		"e-mail":                 `"e mail"`,
+		`c++ "or" and`:           `(("c" AND "or") AND "and")`,
+	}
+	for in, want := range cases {
+		q, err := task.ParseSearch(in)
+		if err != nil {
+			t.Errorf("ParseSearch(%q) unexpected error: %v", in, err)
+			continue
+		}
+		if got := q.FTS(); got != want {
+			t.Errorf("ParseSearch(%q).FTS() = %s; want %s", in, got, want)
+		}
+	}
+
+	for _, in := range []string{"", "  ", "NOT milk", "buy OR", "(buy", "buy)", `"buy`, "++", "AND"} {
+		if _, err := task.ParseSearch(in); !errors.Is(err, task.ErrInvalidSearch) {
+			t.Errorf("ParseSearch(%q) expected ErrInvalidSearch, got %v", in, err)
+		}
+	}
+}
+
+func TestSearchQuery_Highlight(t *testing.T) {
+	mark := func(s string) string { return "[" + s + "]" }
+	cases := []struct{ query, text, want string }{
+		{"milk", "Buy Milk, and milkshakes", "Buy [Milk], and milkshakes"},
+		{"milk*", "Buy Milk, and milkshakes", "Buy [Milk], and [milkshakes]"},
+		{`"buy milk"`, "buy milk, not buy bread", "[buy milk], not buy bread"},
+		{"buy NOT bread", "buy milk, not buy bread", "[buy] milk, not [buy] bread"},
+		{"café", "Visit the Café", "Visit the [Café]"},
+	}
+	for _, c := range cases {
+		q, _ := task.ParseSearch(c.query)
+		if got := q.Highlight(c.text, mark); got != c.want {
+			t.Errorf("Highlight(%q, %q) = %q; want %q", c.query, c.text, got, c.want)
+		}
+	}
+}
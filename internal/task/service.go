@@ -2,58 +2,823 @@ package task
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 )
 
+// ErrStorage is wrapped by the "failed to ..." errors
+// of the service, the ones of its repository
+var ErrStorage = errors.New("storage failure")
+
+// storageErrorf formats the error of the repository
+// wrapped by the service, which also wraps ErrStorage
+func storageErrorf(format string, a ...any) error {
+	return &storageError{fmt.Errorf(format, a...)}
+}
+
+type storageError struct {
+	err error
+}
+
+func (e *storageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *storageError) Unwrap() []error {
+	return []error{e.err, ErrStorage}
+}
+
 type Service struct {
 	r Repository
+	// now is the clock the relative dates are resolved against
+	now func() time.Time
 }
 
 func NewService(r Repository) *Service {
-	return &Service{r}
+	return &Service{r: r, now: time.Now}
 }
 
-func (s *Service) Create(ctx context.Context, desc []string) (tasks []Task, err error) {
-	var errs []string
+// SetClock replaces the clock of the service, for the tests
+func (s *Service) SetClock(now func() time.Time) {
+	s.now = now
+}
+
+func (s *Service) Create(ctx context.Context, desc []string, opts CreateOptions) (tasks []Task, err error) {
+	var project *Project
+	if opts.Project != "" {
+		p, err := s.activeProject(ctx, opts.Project)
+		if err != nil {
+			return nil, err
+		}
+		project = &p
+	}
+	var parentID *uint
+	if opts.Parent != 0 {
+		parent, err := s.getActive(ctx, opts.Parent)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidParent, err)
+		}
+		parentID = &parent.ID
+	}
+
+	recurrence, err := ParseRecurrence(opts.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	var rule string
+	if recurrence != nil {
+		rule = recurrence.String()
+	}
+
+	var errs []error
+	now := s.now()
 	for _, d := range desc {
-		t := Task{Description: d}
-		if err := t.validate(); err != nil {
-			errs = append(errs, fmt.Sprintf("task '%s': %v", t.Description, err))
+		t := Task{
+			Description: d,
+			Notes:       opts.Notes,
+			Priority:    opts.Priority,
+			DueAt:       opts.DueAt,
+			Recurrence:  rule,
+			Project:     project,
+			ParentID:    parentID,
+		}
+		names := opts.Tags
+		var err error
+		if !opts.Raw {
+			names, err = s.quickAdd(ctx, &t, now)
+			names = slices.Concat(opts.Tags, names)
+		}
+		var tags []string
+		if err == nil {
+			tags, err = normalizeTags(names)
+		}
+		if err == nil {
+			t.Tags = tagsOf(tags)
+			err = t.validate()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("task '%s': %w", t.Description, err))
 			continue
 		}
 		tasks = append(tasks, t)
 	}
 	if len(errs) > 0 {
-		return tasks, fmt.Errorf("validation errors: %s", strings.Join(errs, "; "))
+		err := errs[0]
+		for _, e := range errs[1:] {
+			err = fmt.Errorf("%w; %w", err, e)
+		}
+		return tasks, fmt.Errorf("validation errors: %w", err)
 	}
 
 	if err := s.r.Create(ctx, tasks); err != nil {
-		return nil, err
+		return nil, storageErrorf("failed to create tasks: %w", err)
 	}
 	return tasks, nil
 }
 
+// quickAdd sets the fields of t written in its description, see
+// ParseQuickAdd, and returns the tags found there
+func (s *Service) quickAdd(ctx context.Context, t *Task, now time.Time) ([]string, error) {
+	q := ParseQuickAdd(t.Description, now)
+	t.Description, t.Context = q.Description, q.Context
+	if q.Priority != nil {
+		t.Priority = *q.Priority
+	}
+	if q.DueAt != nil {
+		t.DueAt = q.DueAt
+	}
+	if q.Project != "" {
+		p, err := s.activeProject(ctx, q.Project)
+		if err != nil {
+			return nil, err
+		}
+		t.Project = &p
+	}
+	return q.Tags, nil
+}
+
 func (s *Service) Delete(ctx context.Context, ids []int) (affected int, err error) {
+	if err := s.cascade(ctx, ids, notRemoved, s.r.Delete); err != nil {
+		return 0, storageErrorf("failed to delete subtasks: %w", err)
+	}
 	affected, err = s.r.Delete(ctx, ids)
 	if err != nil {
-		return 0, fmt.Errorf("failed to delete tasks: %w", err)
+		return 0, storageErrorf("failed to delete tasks: %w", err)
 	}
 	return affected, nil
 }
 
-func (s *Service) List(ctx context.Context, ids []int, filter ListFilter) (tasks []Task, err error) {
-	tasks, err = s.r.Get(ctx, ids, filter)
+func (s *Service) List(ctx context.Context, ids []int, filter ListFilter, opts ListOptions) (tasks []Task, err error) {
+	if opts.Tags, err = normalizeTags(opts.Tags); err != nil {
+		return nil, err
+	}
+	tasks, err = s.r.Get(ctx, ids, filter, opts)
+	if err != nil {
+		return nil, storageErrorf("failed to list tasks: %w", err)
+	}
+	return tasks, nil
+}
+
+// Search returns the tasks of filter matching the query, the best matches first
+func (s *Service) Search(ctx context.Context, query SearchQuery, filter ListFilter) ([]Task, error) {
+	tasks, err := s.r.Search(ctx, query, filter)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list tasks: %w", err)
+		return nil, storageErrorf("failed to search tasks: %w", err)
 	}
 	return tasks, nil
 }
 
-func (s *Service) Complete(ctx context.Context, ids []int) (affected int, err error) {
-	affected, err = s.r.Complete(ctx, ids)
+// Complete completes the tasks. The tasks with open subtasks are skipped
+// unless opts.Recursive, and the tasks blocked by open tasks, or with an
+// open subtask that is, are skipped unless opts.Force. The number of
+// completed tasks is returned together with ErrOpenSubtasks and
+// ErrBlocked errors listing the skipped ones. Completing a recurrent
+// task creates its next occurrence, unless it is open
+func (s *Service) Complete(ctx context.Context, ids []int, opts CompleteOptions) (affected int, err error) {
+	descendants, err := s.r.Descendants(ctx, ids)
+	if err != nil {
+		return 0, storageErrorf("failed to complete tasks: %w", err)
+	}
+	tree := subtree(ids, descendants)
+	var blockers map[int][]int
+	if !opts.Force {
+		checked := ids
+		if opts.Recursive {
+			checked = append(slices.Clone(ids), idsOf(descendants, open)...)
+		}
+		if blockers, err = s.openBlockers(ctx, checked); err != nil {
+			return 0, storageErrorf("failed to complete tasks: %w", err)
+		}
+	}
+
+	var withSubtasks, blocked []string
+	var completable, subtasks []int
+	for _, id := range ids {
+		if b := blockers[id]; len(b) > 0 {
+			blocked = append(blocked, fmt.Sprintf("task %d (by %s)", id, joinIDs(b, ", ")))
+			continue
+		}
+		// the subtasks given too are completed, and counted, with the ids
+		openSubtasks := idsOf(tree[uint(id)], func(t Task) bool {
+			return open(t) && !slices.Contains(ids, int(t.ID))
+		})
+		if len(openSubtasks) > 0 && !opts.Recursive {
+			withSubtasks = append(withSubtasks, fmt.Sprintf("task %d (%d open)", id, len(openSubtasks)))
+			continue
+		}
+		var blockedSubtasks []string
+		for _, sub := range openSubtasks {
+			if b := blockers[sub]; len(b) > 0 {
+				blockedSubtasks = append(blockedSubtasks, fmt.Sprintf("subtask %d by %s", sub, joinIDs(b, ", ")))
+			}
+		}
+		if len(blockedSubtasks) > 0 {
+			blocked = append(blocked, fmt.Sprintf("task %d (%s)", id, strings.Join(blockedSubtasks, "; ")))
+			continue
+		}
+		completable = append(completable, id)
+		subtasks = append(subtasks, openSubtasks...)
+	}
+
+	var completing []Task
+	if ids := slices.Concat(subtasks, completable); len(ids) > 0 {
+		tasks, err := s.r.Get(ctx, ids, IDs, ListOptions{})
+		if err != nil {
+			return 0, storageErrorf("failed to complete tasks: %w", err)
+		}
+		// the repository only completes the tasks that are not yet
+		for _, t := range tasks {
+			if t.CompletedAt == nil {
+				completing = append(completing, t)
+			}
+		}
+	}
+	if len(subtasks) > 0 {
+		if _, err := s.r.Complete(ctx, subtasks); err != nil {
+			return 0, storageErrorf("failed to complete subtasks: %w", err)
+		}
+	}
+	affected, err = s.r.Complete(ctx, completable)
+	if err != nil {
+		return 0, storageErrorf("failed to complete tasks: %w", err)
+	}
+	if err := s.repeat(ctx, completing, s.now()); err != nil {
+		return affected, err
+	}
+
+	var skipped []error
+	if len(withSubtasks) > 0 {
+		skipped = append(skipped, fmt.Errorf("%w: %s", ErrOpenSubtasks, strings.Join(withSubtasks, ", ")))
+	}
+	if len(blocked) > 0 {
+		skipped = append(skipped, fmt.Errorf("%w: %s", ErrBlocked, strings.Join(blocked, ", ")))
+	}
+	return affected, errors.Join(skipped...)
+}
+
+// Uncomplete reopens the tasks. The next occurrences of the recurrent
+// ones are kept, and not created again when they are completed again
+func (s *Service) Uncomplete(ctx context.Context, ids []int) (affected int, err error) {
+	affected, err = s.r.Uncomplete(ctx, ids)
+	if err != nil {
+		return 0, storageErrorf("failed to uncomplete tasks: %w", err)
+	}
+	return affected, nil
+}
+
+func (s *Service) Restore(ctx context.Context, ids []int) (affected int, err error) {
+	if err := s.cascade(ctx, ids, removed, s.r.Restore); err != nil {
+		return 0, storageErrorf("failed to restore subtasks: %w", err)
+	}
+	affected, err = s.r.Restore(ctx, ids)
+	if err != nil {
+		return 0, storageErrorf("failed to restore tasks: %w", err)
+	}
+	return affected, nil
+}
+
+// Purge deletes permanently the removed tasks with the given ids. When no ids
+// are given every removed task is a candidate. A non zero olderThan only keeps
+// the candidates removed before that long ago, along with their subtasks, and
+// skips the ones with a subtask removed since. It returns the number of purged
+// tasks and the number of candidates
+func (s *Service) Purge(ctx context.Context, ids []int, olderThan time.Duration) (affected, total int, err error) {
+	filter := Removed
+	if len(ids) > 0 {
+		filter = IDs
+	}
+	tasks, err := s.r.Get(ctx, ids, filter, ListOptions{})
+	if err != nil {
+		return 0, 0, storageErrorf("failed to purge tasks: %w", err)
+	}
+
+	total = len(ids)
+	cutoff := s.now().Add(-olderThan)
+	purgeable := removed
+	if olderThan > 0 {
+		purgeable = func(t Task) bool { return removed(t) && !t.DeletedAt.After(cutoff) }
+	}
+	candidates := idsOf(tasks, purgeable)
+	if olderThan > 0 && len(candidates) > 0 {
+		if candidates, err = s.withoutRecentSubtasks(ctx, candidates, purgeable); err != nil {
+			return 0, total, storageErrorf("failed to purge tasks: %w", err)
+		}
+	}
+	if len(ids) == 0 {
+		total = len(candidates)
+	}
+	if len(candidates) == 0 {
+		return 0, total, nil
+	}
+
+	if err := s.cascade(ctx, candidates, purgeable, s.r.Purge); err != nil {
+		return 0, total, storageErrorf("failed to purge subtasks: %w", err)
+	}
+	affected, err = s.r.Purge(ctx, candidates)
+	if err != nil {
+		return 0, total, storageErrorf("failed to purge tasks: %w", err)
+	}
+	return affected, total, nil
+}
+
+// withoutRecentSubtasks drops the ids with a removed subtask that is not
+// purgeable, which would be left behind by its purged parent
+func (s *Service) withoutRecentSubtasks(ctx context.Context, ids []int, purgeable func(Task) bool) ([]int, error) {
+	descendants, err := s.r.Descendants(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	parents := map[uint]uint{}
+	for _, t := range descendants {
+		if t.ParentID != nil {
+			parents[t.ID] = *t.ParentID
+		}
+	}
+	kept := map[int]bool{}
+	for _, t := range descendants {
+		if !removed(t) || purgeable(t) {
+			continue
+		}
+		for id, ok := parents[t.ID]; ok; id, ok = parents[id] {
+			kept[int(id)] = true
+		}
+	}
+	return slices.DeleteFunc(ids, func(id int) bool { return kept[id] }), nil
+}
+
+func (s *Service) Edit(ctx context.Context, id int, desc string) (Task, error) {
+	return s.update(ctx, id, "edit", func(t *Task) {
+		t.Description = desc
+	})
+}
+
+// Change replaces every editable field of the task at once
+func (s *Service) Change(ctx context.Context, id int, ch Changes) (Task, error) {
+	var project *Project
+	if ch.Project != "" {
+		p, err := s.activeProject(ctx, ch.Project)
+		if err != nil {
+			return Task{}, err
+		}
+		project = &p
+	}
+	recurrence, err := ParseRecurrence(ch.Recurrence)
+	if err != nil {
+		return Task{}, err
+	}
+	var rule string
+	if recurrence != nil {
+		rule = recurrence.String()
+	}
+	tags, err := normalizeTags(ch.Tags)
+	if err != nil {
+		return Task{}, err
+	}
+
+	var old []Tag
+	_, err = s.update(ctx, id, "change", func(t *Task) {
+		old = t.Tags
+		t.Description = ch.Description
+		t.Notes = ch.Notes
+		t.Priority = ch.Priority
+		t.DueAt = ch.DueAt
+		t.Recurrence = rule
+		t.Project, t.ProjectID = project, nil
+		if project != nil {
+			t.ProjectID = &project.ID
+		}
+		t.Tags = tagsOf(tags)
+	})
+	if err != nil {
+		return Task{}, err
+	}
+
+	var removed []string
+	for _, tag := range old {
+		if !slices.Contains(tags, tag.Name) {
+			removed = append(removed, tag.Name)
+		}
+	}
+	if len(removed) > 0 {
+		if err := s.r.RemoveTags(ctx, id, removed); err != nil {
+			return Task{}, storageErrorf("failed to untag task %d: %w", id, err)
+		}
+	}
+	if len(tags) > 0 {
+		if err := s.r.AddTags(ctx, id, tags); err != nil {
+			return Task{}, storageErrorf("failed to tag task %d: %w", id, err)
+		}
+	}
+	return s.getActive(ctx, id)
+}
+
+func (s *Service) Prioritize(ctx context.Context, id int, p Priority) (Task, error) {
+	return s.update(ctx, id, "prioritize", func(t *Task) {
+		t.Priority = p
+	})
+}
+
+func (s *Service) Tag(ctx context.Context, id int, names []string) (Task, error) {
+	tags, err := normalizeTags(names)
+	if err != nil {
+		return Task{}, err
+	}
+	if _, err := s.getActive(ctx, id); err != nil {
+		return Task{}, err
+	}
+
+	if err := s.r.AddTags(ctx, id, tags); err != nil {
+		return Task{}, storageErrorf("failed to tag task %d: %w", id, err)
+	}
+	return s.getActive(ctx, id)
+}
+
+func (s *Service) Untag(ctx context.Context, id int, names []string) (Task, error) {
+	tags, err := normalizeTags(names)
+	if err != nil {
+		return Task{}, err
+	}
+	if _, err := s.getActive(ctx, id); err != nil {
+		return Task{}, err
+	}
+
+	if err := s.r.RemoveTags(ctx, id, tags); err != nil {
+		return Task{}, storageErrorf("failed to untag task %d: %w", id, err)
+	}
+	return s.getActive(ctx, id)
+}
+
+func (s *Service) Tags(ctx context.Context) ([]TagCount, error) {
+	tags, err := s.r.Tags(ctx)
+	if err != nil {
+		return nil, storageErrorf("failed to list tags: %w", err)
+	}
+	return tags, nil
+}
+
+func (s *Service) AddProject(ctx context.Context, name string) (Project, error) {
+	p := Project{Name: strings.TrimSpace(name)}
+	if err := p.validate(); err != nil {
+		return Project{}, err
+	}
+	if err := s.checkProjectName(ctx, p.Name); err != nil {
+		return Project{}, err
+	}
+
+	if err := s.r.CreateProject(ctx, &p); err != nil {
+		return Project{}, storageErrorf("failed to add project: %w", err)
+	}
+	return p, nil
+}
+
+func (s *Service) Projects(ctx context.Context) ([]Project, error) {
+	projects, err := s.r.GetProjects(ctx)
+	if err != nil {
+		return nil, storageErrorf("failed to list projects: %w", err)
+	}
+	return projects, nil
+}
+
+func (s *Service) RenameProject(ctx context.Context, name, newName string) (Project, error) {
+	p, err := s.getProject(ctx, name)
+	if err != nil {
+		return Project{}, err
+	}
+	p.Name = strings.TrimSpace(newName)
+	if err := p.validate(); err != nil {
+		return Project{}, err
+	}
+	if err := s.checkProjectName(ctx, p.Name); err != nil {
+		return Project{}, err
+	}
+
+	if err := s.r.UpdateProject(ctx, p); err != nil {
+		return Project{}, storageErrorf("failed to rename project '%s': %w", name, err)
+	}
+	return p, nil
+}
+
+// ArchiveProject hides the tasks of the project from the
+// uncompleted listing and does not allow adding new ones
+func (s *Service) ArchiveProject(ctx context.Context, name string) (Project, error) {
+	p, err := s.activeProject(ctx, name)
+	if err != nil {
+		return Project{}, err
+	}
+	now := s.now()
+	p.ArchivedAt = &now
+
+	if err := s.r.UpdateProject(ctx, p); err != nil {
+		return Project{}, storageErrorf("failed to archive project '%s': %w", name, err)
+	}
+	return p, nil
+}
+
+// Assign moves the tasks to the project with the given
+// name, an empty name takes them out of their project
+func (s *Service) Assign(ctx context.Context, ids []int, name string) (affected int, err error) {
+	var projectID *uint
+	if name != "" {
+		p, err := s.activeProject(ctx, name)
+		if err != nil {
+			return 0, err
+		}
+		projectID = &p.ID
+	}
+
+	affected, err = s.r.AssignProject(ctx, ids, projectID)
+	if err != nil {
+		return 0, storageErrorf("failed to assign tasks: %w", err)
+	}
+	return affected, nil
+}
+
+func (s *Service) getProject(ctx context.Context, name string) (Project, error) {
+	p, err := s.r.GetProject(ctx, name)
+	if errors.Is(err, ErrProjectNotFound) {
+		return Project{}, fmt.Errorf("project '%s': %w", name, err)
+	}
+	if err != nil {
+		return Project{}, storageErrorf("failed to get project '%s': %w", name, err)
+	}
+	return p, nil
+}
+
+// activeProject returns the project with the given
+// name as long as it has not been archived
+func (s *Service) activeProject(ctx context.Context, name string) (Project, error) {
+	p, err := s.getProject(ctx, name)
+	if err != nil {
+		return Project{}, err
+	}
+	if p.Archived() {
+		return Project{}, fmt.Errorf("project '%s': %w", name, ErrProjectArchived)
+	}
+	return p, nil
+}
+
+// checkProjectName fails if the name is already used by a project
+func (s *Service) checkProjectName(ctx context.Context, name string) error {
+	_, err := s.r.GetProject(ctx, name)
+	if err == nil {
+		return fmt.Errorf("project '%s': %w", name, ErrProjectExists)
+	}
+	if !errors.Is(err, ErrProjectNotFound) {
+		return storageErrorf("failed to get project '%s': %w", name, err)
+	}
+	return nil
+}
+
+// Move makes the task a subtask of parent,
+// a 0 parent moves it to the top level
+func (s *Service) Move(ctx context.Context, id, parent int) (Task, error) {
+	var parentID *uint
+	if parent != 0 {
+		if parent == id {
+			return Task{}, fmt.Errorf("%w: task %d cannot be its own parent", ErrInvalidParent, id)
+		}
+		p, err := s.getActive(ctx, parent)
+		if err != nil {
+			return Task{}, fmt.Errorf("%w: %w", ErrInvalidParent, err)
+		}
+		descendants, err := s.r.Descendants(ctx, []int{id})
+		if err != nil {
+			return Task{}, storageErrorf("failed to move task %d: %w", id, err)
+		}
+		if slices.Contains(idsOf(descendants, notRemoved), parent) {
+			return Task{}, fmt.Errorf("%w: task %d is a subtask of task %d", ErrInvalidParent, parent, id)
+		}
+		parentID = &p.ID
+	}
+
+	return s.update(ctx, id, "move", func(t *Task) {
+		t.ParentID = parentID
+	})
+}
+
+// Progress returns the roll-up of the not removed
+// subtasks of every task that has any
+func (s *Service) Progress(ctx context.Context, tasks []Task) (map[uint]Progress, error) {
+	ids := idsOf(tasks, func(Task) bool { return true })
+	descendants, err := s.r.Descendants(ctx, ids)
+	if err != nil {
+		return nil, storageErrorf("failed to get subtasks: %w", err)
+	}
+
+	progress := map[uint]Progress{}
+	for root, subtasks := range subtree(ids, descendants) {
+		var p Progress
+		for _, t := range subtasks {
+			if t.DeletedAt != nil {
+				continue
+			}
+			p.Total++
+			if t.CompletedAt != nil {
+				p.Done++
+			}
+		}
+		if p.Total > 0 {
+			progress[root] = p
+		}
+	}
+	return progress, nil
+}
+
+// cascade applies action to the subtasks of the
+// given tasks that pass keep
+func (s *Service) cascade(ctx context.Context, ids []int, keep func(Task) bool, action func(context.Context, []int) (int, error)) error {
+	descendants, err := s.r.Descendants(ctx, ids)
 	if err != nil {
-		return 0, fmt.Errorf("failed to complete tasks: %w", err)
+		return err
+	}
+	subtasks := idsOf(descendants, func(t Task) bool {
+		return keep(t) && !slices.Contains(ids, int(t.ID))
+	})
+	if len(subtasks) == 0 {
+		return nil
+	}
+	_, err = action(ctx, subtasks)
+	return err
+}
+
+// Block makes the task wait for the blockers to be completed,
+// failing with a DependencyCycleError if any blocker already
+// depends on the task
+func (s *Service) Block(ctx context.Context, id int, blockers []int) error {
+	if _, err := s.getActive(ctx, id); err != nil {
+		return err
+	}
+	deps, err := s.r.Dependencies(ctx)
+	if err != nil {
+		return storageErrorf("failed to block task %d: %w", id, err)
+	}
+	for _, b := range blockers {
+		if _, err := s.getActive(ctx, b); err != nil {
+			return fmt.Errorf("blocker: %w", err)
+		}
+		if path := blockerPath(deps, b, id); path != nil {
+			return &DependencyCycleError{Path: append([]int{id}, path...)}
+		}
+	}
+
+	if err := s.r.AddDependencies(ctx, id, blockers); err != nil {
+		return storageErrorf("failed to block task %d: %w", id, err)
+	}
+	return nil
+}
+
+func (s *Service) Unblock(ctx context.Context, id int, blockers []int) (affected int, err error) {
+	affected, err = s.r.RemoveDependencies(ctx, id, blockers)
+	if err != nil {
+		return 0, storageErrorf("failed to unblock task %d: %w", id, err)
 	}
 	return affected, nil
 }
+
+// openBlockers returns the uncompleted blockers of each task
+func (s *Service) openBlockers(ctx context.Context, ids []int) (map[int][]int, error) {
+	deps, err := s.r.Dependencies(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var blockerIDs []int
+	for _, d := range deps {
+		if slices.Contains(ids, int(d.TaskID)) {
+			blockerIDs = append(blockerIDs, int(d.BlockerID))
+		}
+	}
+	if len(blockerIDs) == 0 {
+		return nil, nil
+	}
+	tasks, err := s.r.Get(ctx, blockerIDs, IDs, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	openIDs := idsOf(tasks, open)
+
+	blockers := map[int][]int{}
+	for _, d := range deps {
+		if slices.Contains(ids, int(d.TaskID)) && slices.Contains(openIDs, int(d.BlockerID)) {
+			blockers[int(d.TaskID)] = append(blockers[int(d.TaskID)], int(d.BlockerID))
+		}
+	}
+	return blockers, nil
+}
+
+func (s *Service) StopRecurrence(ctx context.Context, id int) (Task, error) {
+	return s.update(ctx, id, "stop recurrence of", func(t *Task) {
+		t.Recurrence = ""
+	})
+}
+
+// repeat creates the next occurrence of the recurrent tasks completed
+// at completedAt, unless it is already open, e.g. when the task was
+// reopened and completed again
+func (s *Service) repeat(ctx context.Context, completed []Task, completedAt time.Time) error {
+	var next []Task
+	for _, t := range completed {
+		if n := t.next(completedAt); n != nil {
+			next = append(next, *n)
+		}
+	}
+	if len(next) == 0 {
+		return nil
+	}
+	tasks, err := s.r.Get(ctx, nil, All, ListOptions{})
+	if err != nil {
+		return storageErrorf("failed to get the open tasks: %w", err)
+	}
+	next = slices.DeleteFunc(next, func(n Task) bool {
+		return slices.ContainsFunc(tasks, func(t Task) bool { return open(t) && t.occurrenceOf(n) })
+	})
+	if len(next) == 0 {
+		return nil
+	}
+	if err := s.r.Create(ctx, next); err != nil {
+		return storageErrorf("failed to create the next occurrences: %w", err)
+	}
+	return nil
+}
+
+// SetDue changes the due date of a task, a nil due removes it
+func (s *Service) SetDue(ctx context.Context, id int, due *time.Time) (Task, error) {
+	return s.update(ctx, id, "set due date of", func(t *Task) {
+		t.DueAt = due
+	})
+}
+
+// update applies change to an active task,
+// validates the result and saves it
+func (s *Service) update(ctx context.Context, id int, action string, change func(t *Task)) (Task, error) {
+	t, err := s.getActive(ctx, id)
+	if err != nil {
+		return Task{}, err
+	}
+	change(&t)
+	if err := t.validate(); err != nil {
+		return Task{}, err
+	}
+
+	if err := s.r.Update(ctx, t); err != nil {
+		return Task{}, storageErrorf("failed to %s task %d: %w", action, id, err)
+	}
+	return t, nil
+}
+
+// Show returns a single task with all its details,
+// even if it has been removed
+func (s *Service) Show(ctx context.Context, id int) (Task, error) {
+	return s.get(ctx, id)
+}
+
+// SetNotes replaces the notes of the task
+func (s *Service) SetNotes(ctx context.Context, id int, notes string) (Task, error) {
+	return s.update(ctx, id, "set notes of", func(t *Task) {
+		t.Notes = notes
+	})
+}
+
+// Annotate appends a timestamped annotation to the task
+func (s *Service) Annotate(ctx context.Context, id int, text string) (Task, error) {
+	a := Annotation{TaskID: uint(id), Text: strings.TrimSpace(text)}
+	if err := a.validate(); err != nil {
+		return Task{}, err
+	}
+	if _, err := s.getActive(ctx, id); err != nil {
+		return Task{}, err
+	}
+
+	if err := s.r.AddAnnotation(ctx, &a); err != nil {
+		return Task{}, storageErrorf("failed to annotate task %d: %w", id, err)
+	}
+	return s.getActive(ctx, id)
+}
+
+func (s *Service) get(ctx context.Context, id int) (Task, error) {
+	tasks, err := s.r.Get(ctx, []int{id}, IDs, ListOptions{})
+	if err != nil {
+		return Task{}, storageErrorf("failed to get task %d: %w", id, err)
+	}
+	for _, t := range tasks {
+		if int(t.ID) == id {
+			return t, nil
+		}
+	}
+	return Task{}, fmt.Errorf("task %d: %w", id, ErrTaskNotFound)
+}
+
+// getActive returns the task with the given id as long as it
+// exists and it has not been removed
+func (s *Service) getActive(ctx context.Context, id int) (Task, error) {
+	t, err := s.get(ctx, id)
+	if err != nil {
+		return Task{}, err
+	}
+	if t.DeletedAt != nil {
+		return Task{}, fmt.Errorf("task %d: %w", id, ErrTaskRemoved)
+	}
+	return t, nil
+}
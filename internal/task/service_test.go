@@ -3,18 +3,42 @@ package task_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"arcedo/cli-todo/internal/task"
 )
 
 // mocks
 type mockRepository struct {
-	createFunc   func(ctx context.Context, tasks []task.Task) error
-	deleteFunc   func(ctx context.Context, ids []int) (int, error)
-	getFunc      func(ctx context.Context, ids []int, filter task.ListFilter) ([]task.Task, error)
-	completeFunc func(ctx context.Context, ids []int) (int, error)
+	createFunc      func(ctx context.Context, tasks []task.Task) error
+	deleteFunc      func(ctx context.Context, ids []int) (int, error)
+	getFunc         func(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error)
+	searchFunc      func(ctx context.Context, query task.SearchQuery, filter task.ListFilter) ([]task.Task, error)
+	completeFunc    func(ctx context.Context, ids []int) (int, error)
+	uncompleteFunc  func(ctx context.Context, ids []int) (int, error)
+	addTagsFunc     func(ctx context.Context, id int, tags []string) error
+	removeTagsFunc  func(ctx context.Context, id int, tags []string) error
+	tagsFunc        func(ctx context.Context) ([]task.TagCount, error)
+	updateFunc      func(ctx context.Context, t task.Task) error
+	restoreFunc     func(ctx context.Context, ids []int) (int, error)
+	purgeFunc       func(ctx context.Context, ids []int) (int, error)
+	descendantsFunc func(ctx context.Context, ids []int) ([]task.Task, error)
+
+	addDependenciesFunc    func(ctx context.Context, id int, blockers []int) error
+	removeDependenciesFunc func(ctx context.Context, id int, blockers []int) (int, error)
+	dependenciesFunc       func(ctx context.Context) ([]task.Dependency, error)
+
+	createProjectFunc func(ctx context.Context, p *task.Project) error
+	getProjectFunc    func(ctx context.Context, name string) (task.Project, error)
+	getProjectsFunc   func(ctx context.Context) ([]task.Project, error)
+	updateProjectFunc func(ctx context.Context, p task.Project) error
+	assignProjectFunc func(ctx context.Context, ids []int, projectID *uint) (int, error)
+
+	addAnnotationFunc func(ctx context.Context, a *task.Annotation) error
 }
 
 func (m *mockRepository) Create(ctx context.Context, tasks []task.Task) error {
@@ -25,14 +49,98 @@ func (m *mockRepository) Delete(ctx context.Context, ids []int) (int, error) {
 	return m.deleteFunc(ctx, ids)
 }
 
-func (m *mockRepository) Get(ctx context.Context, ids []int, filter task.ListFilter) ([]task.Task, error) {
-	return m.getFunc(ctx, ids, filter)
+func (m *mockRepository) Get(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error) {
+	return m.getFunc(ctx, ids, filter, opts)
+}
+
+func (m *mockRepository) Search(ctx context.Context, query task.SearchQuery, filter task.ListFilter) ([]task.Task, error) {
+	return m.searchFunc(ctx, query, filter)
 }
 
 func (m *mockRepository) Complete(ctx context.Context, ids []int) (int, error) {
 	return m.completeFunc(ctx, ids)
 }
 
+func (m *mockRepository) Descendants(ctx context.Context, ids []int) ([]task.Task, error) {
+	return m.descendantsFunc(ctx, ids)
+}
+
+func (m *mockRepository) AddDependencies(ctx context.Context, id int, blockers []int) error {
+	return m.addDependenciesFunc(ctx, id, blockers)
+}
+
+func (m *mockRepository) RemoveDependencies(ctx context.Context, id int, blockers []int) (int, error) {
+	return m.removeDependenciesFunc(ctx, id, blockers)
+}
+
+func (m *mockRepository) Dependencies(ctx context.Context) ([]task.Dependency, error) {
+	return m.dependenciesFunc(ctx)
+}
+
+func (m *mockRepository) AddTags(ctx context.Context, id int, tags []string) error {
+	return m.addTagsFunc(ctx, id, tags)
+}
+
+func (m *mockRepository) RemoveTags(ctx context.Context, id int, tags []string) error {
+	return m.removeTagsFunc(ctx, id, tags)
+}
+
+func (m *mockRepository) Tags(ctx context.Context) ([]task.TagCount, error) {
+	return m.tagsFunc(ctx)
+}
+
+func (m *mockRepository) CreateProject(ctx context.Context, p *task.Project) error {
+	return m.createProjectFunc(ctx, p)
+}
+
+func (m *mockRepository) GetProject(ctx context.Context, name string) (task.Project, error) {
+	return m.getProjectFunc(ctx, name)
+}
+
+func (m *mockRepository) GetProjects(ctx context.Context) ([]task.Project, error) {
+	return m.getProjectsFunc(ctx)
+}
+
+func (m *mockRepository) UpdateProject(ctx context.Context, p task.Project) error {
+	return m.updateProjectFunc(ctx, p)
+}
+
+func (m *mockRepository) AssignProject(ctx context.Context, ids []int, projectID *uint) (int, error) {
+	return m.assignProjectFunc(ctx, ids, projectID)
+}
+
+func (m *mockRepository) Uncomplete(ctx context.Context, ids []int) (int, error) {
+	return m.uncompleteFunc(ctx, ids)
+}
+
+func (m *mockRepository) Update(ctx context.Context, t task.Task) error {
+	return m.updateFunc(ctx, t)
+}
+
+func (m *mockRepository) Restore(ctx context.Context, ids []int) (int, error) {
+	return m.restoreFunc(ctx, ids)
+}
+
+func (m *mockRepository) Purge(ctx context.Context, ids []int) (int, error) {
+	return m.purgeFunc(ctx, ids)
+}
+
+func (m *mockRepository) AddAnnotation(ctx context.Context, a *task.Annotation) error {
+	return m.addAnnotationFunc(ctx, a)
+}
+
+func noDescendants(ctx context.Context, ids []int) ([]task.Task, error) {
+	return nil, nil
+}
+
+func noTasks(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error) {
+	return nil, nil
+}
+
+func noDependencies(ctx context.Context) ([]task.Dependency, error) {
+	return nil, nil
+}
+
 // actual tests
 func TestService_Create(t *testing.T) {
 	ctx := context.Background()
@@ -48,7 +156,7 @@ func TestService_Create(t *testing.T) {
 	svc := task.NewService(mock)
 
 	t.Run("all valid tasks", func(t *testing.T) {
-		tasks, err := svc.Create(ctx, []string{validTask})
+		tasks, err := svc.Create(ctx, []string{validTask}, task.CreateOptions{})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -58,7 +166,7 @@ func TestService_Create(t *testing.T) {
 	})
 
 	t.Run("some invalid tasks", func(t *testing.T) {
-		tasks, err := svc.Create(ctx, []string{validTask, emptyTask})
+		tasks, err := svc.Create(ctx, []string{validTask, emptyTask}, task.CreateOptions{})
 		if err == nil {
 			t.Fatal("expected validation error, got nil")
 		}
@@ -69,11 +177,77 @@ func TestService_Create(t *testing.T) {
 			t.Fatalf("expected only valid task returned, got: %+v", tasks)
 		}
 	})
+
+	t.Run("with priority", func(t *testing.T) {
+		tasks, err := svc.Create(ctx, []string{validTask}, task.CreateOptions{Priority: task.High})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if tasks[0].Priority != task.High {
+			t.Fatalf("expected high priority, got %v", tasks[0].Priority)
+		}
+	})
+
+	t.Run("with tags", func(t *testing.T) {
+		tasks, err := svc.Create(ctx, []string{"Buy milk +Groceries +home"}, task.CreateOptions{Tags: []string{"home"}})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if tasks[0].Description != "Buy milk" {
+			t.Fatalf("expected tags out of the description, got %q", tasks[0].Description)
+		}
+		if len(tasks[0].Tags) != 2 || tasks[0].Tags[0].Name != "home" || tasks[0].Tags[1].Name != "groceries" {
+			t.Fatalf("unexpected tags: %+v", tasks[0].Tags)
+		}
+	})
+
+	t.Run("quick add", func(t *testing.T) {
+		mock.getProjectFunc = func(ctx context.Context, name string) (task.Project, error) {
+			if name != "home" {
+				return task.Project{}, task.ErrProjectNotFound
+			}
+			return task.Project{ID: 1, Name: name}, nil
+		}
+		svc.SetClock(func() time.Time { return time.Date(2026, 10, 21, 10, 0, 0, 0, time.UTC) })
+		defer svc.SetClock(time.Now)
+
+		tasks, err := svc.Create(ctx, []string{"Fix the sink tomorrow 3pm !high @home #home"}, task.CreateOptions{Priority: task.Low})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := tasks[0]
+		if got.Description != "Fix the sink" || got.Priority != task.High || got.Context != "home" || got.Project == nil || got.Project.ID != 1 {
+			t.Fatalf("unexpected task: %+v", got)
+		}
+		if want := time.Date(2026, 10, 22, 15, 0, 0, 0, time.UTC); got.DueAt == nil || !got.DueAt.Equal(want) {
+			t.Fatalf("expected due at %v, got %v", want, got.DueAt)
+		}
+
+		if _, err := svc.Create(ctx, []string{"Plan #garden"}, task.CreateOptions{}); !errors.Is(err, task.ErrProjectNotFound) {
+			t.Fatalf("expected ErrProjectNotFound, got %v", err)
+		}
+
+		tasks, err = svc.Create(ctx, []string{"Call mom tomorrow !high +family"}, task.CreateOptions{Raw: true})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got := tasks[0]; got.Description != "Call mom tomorrow !high +family" || got.DueAt != nil || len(got.Tags) != 0 {
+			t.Fatalf("expected the raw description to be kept, got %+v", got)
+		}
+	})
+
+	t.Run("invalid priority", func(t *testing.T) {
+		_, err := svc.Create(ctx, []string{validTask}, task.CreateOptions{Priority: 9})
+		if err == nil || !strings.Contains(err.Error(), "invalid priority") {
+			t.Fatalf("expected priority validation error, got: %v", err)
+		}
+	})
 }
 
 func TestService_Delete(t *testing.T) {
 	ctx := context.Background()
 	mock := &mockRepository{
+		descendantsFunc: noDescendants,
 		deleteFunc: func(ctx context.Context, ids []int) (int, error) {
 			if len(ids) == 0 {
 				return 0, errors.New("no IDs provided")
@@ -104,7 +278,7 @@ func TestService_Delete(t *testing.T) {
 func TestService_List(t *testing.T) {
 	ctx := context.Background()
 	mock := &mockRepository{
-		getFunc: func(ctx context.Context, ids []int, filter task.ListFilter) ([]task.Task, error) {
+		getFunc: func(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error) {
 			if len(ids) == 0 {
 				return nil, errors.New("no tasks found")
 			}
@@ -117,7 +291,7 @@ func TestService_List(t *testing.T) {
 	svc := task.NewService(mock)
 
 	t.Run("successful list", func(t *testing.T) {
-		tasks, err := svc.List(ctx, []int{1, 2}, task.All)
+		tasks, err := svc.List(ctx, []int{1, 2}, task.All, task.ListOptions{})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -127,7 +301,7 @@ func TestService_List(t *testing.T) {
 	})
 
 	t.Run("list error", func(t *testing.T) {
-		_, err := svc.List(ctx, []int{}, task.All)
+		_, err := svc.List(ctx, []int{}, task.All, task.ListOptions{})
 		if err == nil || !strings.Contains(err.Error(), "failed to list tasks") {
 			t.Fatalf("expected wrapped list error, got: %v", err)
 		}
@@ -137,6 +311,9 @@ func TestService_List(t *testing.T) {
 func TestService_Complete(t *testing.T) {
 	ctx := context.Background()
 	mock := &mockRepository{
+		getFunc:          noTasks,
+		descendantsFunc:  noDescendants,
+		dependenciesFunc: noDependencies,
 		completeFunc: func(ctx context.Context, ids []int) (int, error) {
 			if len(ids) == 0 {
 				return 0, errors.New("no tasks to complete")
@@ -147,7 +324,7 @@ func TestService_Complete(t *testing.T) {
 	svc := task.NewService(mock)
 
 	t.Run("successful complete", func(t *testing.T) {
-		got, err := svc.Complete(ctx, []int{1, 2})
+		got, err := svc.Complete(ctx, []int{1, 2}, task.CompleteOptions{})
 		if err != nil {
 			t.Fatalf("unexpected error: %v", err)
 		}
@@ -157,9 +334,643 @@ func TestService_Complete(t *testing.T) {
 	})
 
 	t.Run("complete error", func(t *testing.T) {
-		_, err := svc.Complete(ctx, []int{})
+		_, err := svc.Complete(ctx, []int{}, task.CompleteOptions{})
 		if err == nil || !strings.Contains(err.Error(), "failed to complete tasks") {
 			t.Fatalf("expected wrapped complete error, got: %v", err)
 		}
 	})
 }
+
+func TestService_Edit(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	var updated task.Task
+	mock := &mockRepository{
+		getFunc: func(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error) {
+			return []task.Task{
+				{ID: 1, Description: "Old"},
+				{ID: 2, Description: "Gone", DeletedAt: &now},
+			}, nil
+		},
+		updateFunc: func(ctx context.Context, t task.Task) error {
+			updated = t
+			return nil
+		},
+	}
+	svc := task.NewService(mock)
+
+	t.Run("successful edit", func(t *testing.T) {
+		got, err := svc.Edit(ctx, 1, "New")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.Description != "New" || updated.Description != "New" {
+			t.Fatalf("expected description updated, got %+v", got)
+		}
+	})
+
+	t.Run("empty description", func(t *testing.T) {
+		_, err := svc.Edit(ctx, 1, " ")
+		if !errors.Is(err, task.ErrEmptyDescription) {
+			t.Fatalf("expected empty description error, got: %v", err)
+		}
+	})
+
+	t.Run("removed task", func(t *testing.T) {
+		_, err := svc.Edit(ctx, 2, "New")
+		if !errors.Is(err, task.ErrTaskRemoved) {
+			t.Fatalf("expected removed error, got: %v", err)
+		}
+	})
+
+	t.Run("unknown task", func(t *testing.T) {
+		_, err := svc.Edit(ctx, 3, "New")
+		if !errors.Is(err, task.ErrTaskNotFound) {
+			t.Fatalf("expected not found error, got: %v", err)
+		}
+	})
+}
+
+func TestService_Notes(t *testing.T) {
+	ctx := context.Background()
+	var updated task.Task
+	var added task.Annotation
+	mock := &mockRepository{
+		getFunc: func(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error) {
+			return []task.Task{{ID: 1, Description: "Task"}}, nil
+		},
+		updateFunc: func(ctx context.Context, t task.Task) error {
+			updated = t
+			return nil
+		},
+		addAnnotationFunc: func(ctx context.Context, a *task.Annotation) error {
+			added = *a
+			return nil
+		},
+	}
+	svc := task.NewService(mock)
+
+	t.Run("set notes", func(t *testing.T) {
+		_, err := svc.SetNotes(ctx, 1, "first line\nsecond line")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if updated.Notes != "first line\nsecond line" {
+			t.Errorf("expected notes updated, got %q", updated.Notes)
+		}
+	})
+
+	t.Run("annotate", func(t *testing.T) {
+		_, err := svc.Annotate(ctx, 1, " called back ")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if added.TaskID != 1 || added.Text != "called back" {
+			t.Errorf("unexpected annotation: %+v", added)
+		}
+	})
+
+	t.Run("empty annotation", func(t *testing.T) {
+		_, err := svc.Annotate(ctx, 1, " ")
+		if !errors.Is(err, task.ErrEmptyAnnotation) {
+			t.Fatalf("expected empty annotation error, got: %v", err)
+		}
+	})
+
+	t.Run("annotate unknown task", func(t *testing.T) {
+		_, err := svc.Annotate(ctx, 2, "text")
+		if !errors.Is(err, task.ErrTaskNotFound) {
+			t.Fatalf("expected not found error, got: %v", err)
+		}
+	})
+}
+
+func TestService_Change(t *testing.T) {
+	ctx := context.Background()
+	var updated task.Task
+	var added, removed []string
+	mock := &mockRepository{
+		getFunc: func(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error) {
+			return []task.Task{{ID: 1, Description: "Old", Tags: []task.Tag{{Name: "home"}, {Name: "work"}}}}, nil
+		},
+		updateFunc: func(ctx context.Context, t task.Task) error {
+			updated = t
+			return nil
+		},
+		addTagsFunc: func(ctx context.Context, id int, tags []string) error {
+			added = tags
+			return nil
+		},
+		removeTagsFunc: func(ctx context.Context, id int, tags []string) error {
+			removed = tags
+			return nil
+		},
+		getProjectFunc: func(ctx context.Context, name string) (task.Project, error) {
+			return task.Project{ID: 4, Name: name}, nil
+		},
+	}
+	svc := task.NewService(mock)
+
+	t.Run("replace every field", func(t *testing.T) {
+		_, err := svc.Change(ctx, 1, task.Changes{
+			Description: "New",
+			Notes:       "notes",
+			Priority:    task.High,
+			Tags:        []string{"+Work", "docs"},
+			Project:     "Office",
+			Recurrence:  "every 1 week",
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if updated.Description != "New" || updated.Notes != "notes" || updated.Priority != task.High ||
+			updated.ProjectID == nil || *updated.ProjectID != 4 || updated.Recurrence != "weekly" {
+			t.Errorf("unexpected update: %+v", updated)
+		}
+		if strings.Join(removed, ",") != "home" || strings.Join(added, ",") != "work,docs" {
+			t.Errorf("expected tags diffed, got removed %v and added %v", removed, added)
+		}
+	})
+
+	t.Run("empty description", func(t *testing.T) {
+		_, err := svc.Change(ctx, 1, task.Changes{Description: " "})
+		if !errors.Is(err, task.ErrEmptyDescription) {
+			t.Fatalf("expected empty description error, got: %v", err)
+		}
+	})
+}
+
+func TestService_Purge(t *testing.T) {
+	ctx := context.Background()
+	old := time.Now().Add(-48 * time.Hour)
+	recent := time.Now()
+	var purged []int
+	mock := &mockRepository{
+		getFunc: func(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error) {
+			return []task.Task{
+				{ID: 1, Description: "Old", DeletedAt: &old},
+				{ID: 2, Description: "Recent", DeletedAt: &recent},
+				{ID: 3, Description: "Active"},
+			}, nil
+		},
+		descendantsFunc: noDescendants,
+		purgeFunc: func(ctx context.Context, ids []int) (int, error) {
+			purged = ids
+			return len(ids), nil
+		},
+	}
+	svc := task.NewService(mock)
+
+	t.Run("purge every removed task", func(t *testing.T) {
+		affected, total, err := svc.Purge(ctx, nil, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if affected != 2 || total != 2 {
+			t.Fatalf("expected 2 of 2 purged, got %d of %d", affected, total)
+		}
+	})
+
+	t.Run("purge older than", func(t *testing.T) {
+		affected, total, err := svc.Purge(ctx, []int{1, 2, 3}, 24*time.Hour)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if affected != 1 || total != 3 || purged[0] != 1 {
+			t.Fatalf("expected only task 1 purged, got %v (%d of %d)", purged, affected, total)
+		}
+	})
+
+	t.Run("keep the subtasks removed since", func(t *testing.T) {
+		one, four := uint(1), uint(4)
+		var calls [][]int
+		mock := &mockRepository{
+			getFunc: func(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error) {
+				return []task.Task{
+					{ID: 1, Description: "Old parent", DeletedAt: &old},
+					{ID: 4, Description: "Old parent", DeletedAt: &old},
+				}, nil
+			},
+			descendantsFunc: func(ctx context.Context, ids []int) ([]task.Task, error) {
+				all := []task.Task{
+					{ID: 2, Description: "Old subtask", ParentID: &one, DeletedAt: &old},
+					{ID: 3, Description: "Recent subtask", ParentID: &one, DeletedAt: &recent},
+					{ID: 5, Description: "Old subtask", ParentID: &four, DeletedAt: &old},
+				}
+				var found []task.Task
+				for _, d := range all {
+					if slices.Contains(ids, int(*d.ParentID)) {
+						found = append(found, d)
+					}
+				}
+				return found, nil
+			},
+			purgeFunc: func(ctx context.Context, ids []int) (int, error) {
+				calls = append(calls, ids)
+				return len(ids), nil
+			},
+		}
+
+		affected, total, err := task.NewService(mock).Purge(ctx, nil, 24*time.Hour)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if affected != 1 || total != 1 || len(calls) != 2 || !slices.Equal(calls[0], []int{5}) || !slices.Equal(calls[1], []int{4}) {
+			t.Fatalf("expected only task 4 and its subtask purged, got %v (%d of %d)", calls, affected, total)
+		}
+	})
+}
+
+func TestParsePriority(t *testing.T) {
+	cases := map[string]task.Priority{
+		"none":   task.NoPriority,
+		"low":    task.Low,
+		"Medium": task.Medium,
+		"h":      task.High,
+	}
+	for in, want := range cases {
+		got, err := task.ParsePriority(in)
+		if err != nil || got != want {
+			t.Errorf("ParsePriority(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+
+	if _, err := task.ParsePriority("urgent"); !errors.Is(err, task.ErrInvalidPriority) {
+		t.Errorf("expected ErrInvalidPriority, got %v", err)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	now := time.Date(2026, 3, 10, 9, 30, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"today":            time.Date(2026, 3, 10, 23, 59, 0, 0, time.UTC),
+		"Tomorrow":         time.Date(2026, 3, 11, 23, 59, 0, 0, time.UTC),
+		"2026-04-01":       time.Date(2026, 4, 1, 23, 59, 0, 0, time.UTC),
+		"2026-04-01 15:04": time.Date(2026, 4, 1, 15, 4, 0, 0, time.UTC),
+		"01/04/2026":       time.Date(2026, 4, 1, 23, 59, 0, 0, time.UTC),
+	}
+	for in, want := range cases {
+		got, err := task.ParseDate(in, now)
+		if err != nil || !got.Equal(want) {
+			t.Errorf("ParseDate(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+
+	if _, err := task.ParseDate("someday", now); !errors.Is(err, task.ErrInvalidDate) {
+		t.Errorf("expected ErrInvalidDate, got %v", err)
+	}
+}
+
+func TestService_Tag(t *testing.T) {
+	ctx := context.Background()
+	var added []string
+	mock := &mockRepository{
+		getFunc: func(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error) {
+			return []task.Task{{ID: 1, Description: "Task"}}, nil
+		},
+		addTagsFunc: func(ctx context.Context, id int, tags []string) error {
+			added = tags
+			return nil
+		},
+	}
+	svc := task.NewService(mock)
+
+	t.Run("normalized tags", func(t *testing.T) {
+		if _, err := svc.Tag(ctx, 1, []string{"+Work", "work", "home"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if strings.Join(added, ",") != "work,home" {
+			t.Fatalf("unexpected tags added: %v", added)
+		}
+	})
+
+	t.Run("invalid tag", func(t *testing.T) {
+		_, err := svc.Tag(ctx, 1, []string{"+"})
+		if !errors.Is(err, task.ErrInvalidTag) {
+			t.Fatalf("expected ErrInvalidTag, got: %v", err)
+		}
+	})
+
+	t.Run("unknown task", func(t *testing.T) {
+		_, err := svc.Tag(ctx, 2, []string{"work"})
+		if !errors.Is(err, task.ErrTaskNotFound) {
+			t.Fatalf("expected ErrTaskNotFound, got: %v", err)
+		}
+	})
+}
+
+func TestService_Projects(t *testing.T) {
+	ctx := context.Background()
+	archived := time.Now()
+	projects := map[string]task.Project{
+		"Home": {ID: 1, Name: "Home"},
+		"Old":  {ID: 2, Name: "Old", ArchivedAt: &archived},
+	}
+	var saved task.Project
+	mock := &mockRepository{
+		getProjectFunc: func(ctx context.Context, name string) (task.Project, error) {
+			if p, ok := projects[name]; ok {
+				return p, nil
+			}
+			return task.Project{}, task.ErrProjectNotFound
+		},
+		updateProjectFunc: func(ctx context.Context, p task.Project) error {
+			saved = p
+			return nil
+		},
+		assignProjectFunc: func(ctx context.Context, ids []int, projectID *uint) (int, error) {
+			return len(ids), nil
+		},
+	}
+	svc := task.NewService(mock)
+
+	t.Run("rename to an existing name", func(t *testing.T) {
+		_, err := svc.RenameProject(ctx, "Home", "Old")
+		if !errors.Is(err, task.ErrProjectExists) {
+			t.Fatalf("expected ErrProjectExists, got: %v", err)
+		}
+	})
+
+	t.Run("rename", func(t *testing.T) {
+		p, err := svc.RenameProject(ctx, "Home", " House ")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if p.Name != "House" || saved.ID != 1 {
+			t.Fatalf("unexpected project saved: %+v", saved)
+		}
+	})
+
+	t.Run("archive", func(t *testing.T) {
+		if _, err := svc.ArchiveProject(ctx, "Home"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !saved.Archived() {
+			t.Fatalf("expected project archived, got %+v", saved)
+		}
+	})
+
+	t.Run("assign to archived project", func(t *testing.T) {
+		_, err := svc.Assign(ctx, []int{1}, "Old")
+		if !errors.Is(err, task.ErrProjectArchived) {
+			t.Fatalf("expected ErrProjectArchived, got: %v", err)
+		}
+	})
+
+	t.Run("assign to unknown project", func(t *testing.T) {
+		_, err := svc.Assign(ctx, []int{1}, "Garden")
+		if !errors.Is(err, task.ErrProjectNotFound) {
+			t.Fatalf("expected ErrProjectNotFound, got: %v", err)
+		}
+	})
+}
+
+func TestService_Subtasks(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	one, two := uint(1), uint(2)
+	tasks := []task.Task{
+		{ID: 1, Description: "Root"},
+		{ID: 2, Description: "Child", ParentID: &one},
+		{ID: 3, Description: "Grandchild", ParentID: &two, CompletedAt: &now},
+		{ID: 4, Description: "Removed child", ParentID: &one, DeletedAt: &now},
+		{ID: 5, Description: "Other"},
+	}
+	var completed, deleted [][]int
+	mock := &mockRepository{
+		getFunc: func(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) ([]task.Task, error) {
+			return tasks, nil
+		},
+		descendantsFunc: func(ctx context.Context, ids []int) (found []task.Task, err error) {
+			parents, seen := map[uint]bool{}, map[uint]bool{}
+			for _, id := range ids {
+				parents[uint(id)] = true
+			}
+			for changed := true; changed; {
+				changed = false
+				for _, tk := range tasks {
+					if tk.ParentID != nil && parents[*tk.ParentID] && !seen[tk.ID] {
+						parents[tk.ID], seen[tk.ID] = true, true
+						found = append(found, tk)
+						changed = true
+					}
+				}
+			}
+			return found, nil
+		},
+		dependenciesFunc: noDependencies,
+		completeFunc: func(ctx context.Context, ids []int) (int, error) {
+			completed = append(completed, ids)
+			return len(ids), nil
+		},
+		deleteFunc: func(ctx context.Context, ids []int) (int, error) {
+			deleted = append(deleted, ids)
+			return len(ids), nil
+		},
+		updateFunc: func(ctx context.Context, t task.Task) error {
+			return nil
+		},
+	}
+	svc := task.NewService(mock)
+
+	t.Run("complete with open subtasks", func(t *testing.T) {
+		completed = nil
+		affected, err := svc.Complete(ctx, []int{1, 5}, task.CompleteOptions{})
+		if !errors.Is(err, task.ErrOpenSubtasks) {
+			t.Fatalf("expected ErrOpenSubtasks, got: %v", err)
+		}
+		if affected != 1 || fmt.Sprint(completed) != "[[5]]" {
+			t.Fatalf("expected only task 5 completed, got %v", completed)
+		}
+	})
+
+	t.Run("complete recursively", func(t *testing.T) {
+		completed = nil
+		if _, err := svc.Complete(ctx, []int{1}, task.CompleteOptions{Recursive: true}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fmt.Sprint(completed) != "[[2] [1]]" {
+			t.Fatalf("expected open subtask completed before its parent, got %v", completed)
+		}
+	})
+
+	t.Run("complete recursively with a blocked subtask", func(t *testing.T) {
+		mock.dependenciesFunc = func(ctx context.Context) ([]task.Dependency, error) {
+			return []task.Dependency{{TaskID: 2, BlockerID: 5}}, nil
+		}
+		defer func() { mock.dependenciesFunc = noDependencies }()
+
+		completed = nil
+		affected, err := svc.Complete(ctx, []int{1}, task.CompleteOptions{Recursive: true})
+		if !errors.Is(err, task.ErrBlocked) || !strings.Contains(err.Error(), "task 1 (subtask 2 by 5)") {
+			t.Fatalf("expected the parent blocked by its subtask, got: %v", err)
+		}
+		if affected != 0 || slices.Contains(slices.Concat(completed...), 2) {
+			t.Fatalf("expected nothing completed, got %v", completed)
+		}
+
+		completed = nil
+		if _, err := svc.Complete(ctx, []int{1}, task.CompleteOptions{Recursive: true, Force: true}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fmt.Sprint(completed) != "[[2] [1]]" {
+			t.Fatalf("expected the subtask forced complete, got %v", completed)
+		}
+	})
+
+	t.Run("complete a task and its subtask", func(t *testing.T) {
+		done := map[int]bool{}
+		mock.completeFunc = func(ctx context.Context, ids []int) (affected int, err error) {
+			completed = append(completed, ids)
+			for _, id := range ids {
+				if !done[id] {
+					done[id] = true
+					affected++
+				}
+			}
+			return affected, nil
+		}
+		defer func() {
+			mock.completeFunc = func(ctx context.Context, ids []int) (int, error) {
+				completed = append(completed, ids)
+				return len(ids), nil
+			}
+		}()
+
+		for _, opts := range []task.CompleteOptions{{Recursive: true}, {}} {
+			clear(done)
+			completed = nil
+			affected, err := svc.Complete(ctx, []int{1, 2}, opts)
+			if err != nil {
+				t.Fatalf("unexpected error with %+v: %v", opts, err)
+			}
+			if affected != 2 || fmt.Sprint(completed) != "[[1 2]]" {
+				t.Fatalf("expected 2 completed with %+v, got %d: %v", opts, affected, completed)
+			}
+		}
+	})
+
+	t.Run("delete cascades", func(t *testing.T) {
+		deleted = nil
+		affected, err := svc.Delete(ctx, []int{1})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if affected != 1 || fmt.Sprint(deleted) != "[[2 3] [1]]" {
+			t.Fatalf("expected subtasks deleted, got %v", deleted)
+		}
+	})
+
+	t.Run("move below its own subtask", func(t *testing.T) {
+		_, err := svc.Move(ctx, 1, 3)
+		if !errors.Is(err, task.ErrInvalidParent) {
+			t.Fatalf("expected ErrInvalidParent, got: %v", err)
+		}
+	})
+
+	t.Run("move to itself", func(t *testing.T) {
+		_, err := svc.Move(ctx, 1, 1)
+		if !errors.Is(err, task.ErrInvalidParent) {
+			t.Fatalf("expected ErrInvalidParent, got: %v", err)
+		}
+	})
+
+	t.Run("move", func(t *testing.T) {
+		got, err := svc.Move(ctx, 5, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got.ParentID == nil || *got.ParentID != 2 {
+			t.Fatalf("expected parent 2, got %v", got.ParentID)
+		}
+	})
+
+	t.Run("progress", func(t *testing.T) {
+		progress, err := svc.Progress(ctx, tasks[:2])
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if progress[1] != (task.Progress{Done: 1, Total: 2}) || progress[2] != (task.Progress{Done: 1, Total: 1}) {
+			t.Fatalf("unexpected progress: %v", progress)
+		}
+	})
+}
+
+func TestService_Dependencies(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	tasks := []task.Task{
+		{ID: 1, Description: "Build"},
+		{ID: 2, Description: "Test"},
+		{ID: 3, Description: "Release"},
+		{ID: 4, Description: "Done", CompletedAt: &now},
+	}
+	// 3 is blocked by 2 that is blocked by 1, 1 is blocked by 4
+	deps := []task.Dependency{{TaskID: 3, BlockerID: 2}, {TaskID: 2, BlockerID: 1}, {TaskID: 1, BlockerID: 4}}
+	var completed []int
+	mock := &mockRepository{
+		getFunc: func(ctx context.Context, ids []int, filter task.ListFilter, opts task.ListOptions) (found []task.Task, err error) {
+			for _, tk := range tasks {
+				if slices.Contains(ids, int(tk.ID)) {
+					found = append(found, tk)
+				}
+			}
+			return found, nil
+		},
+		dependenciesFunc: func(ctx context.Context) ([]task.Dependency, error) {
+			return deps, nil
+		},
+		addDependenciesFunc: func(ctx context.Context, id int, blockers []int) error {
+			return nil
+		},
+		descendantsFunc: noDescendants,
+		completeFunc: func(ctx context.Context, ids []int) (int, error) {
+			completed = ids
+			return len(ids), nil
+		},
+	}
+	svc := task.NewService(mock)
+
+	t.Run("block creating a cycle", func(t *testing.T) {
+		err := svc.Block(ctx, 1, []int{3})
+		var cycle *task.DependencyCycleError
+		if !errors.As(err, &cycle) {
+			t.Fatalf("expected DependencyCycleError, got: %v", err)
+		}
+		if fmt.Sprint(cycle.Path) != "[1 3 2 1]" {
+			t.Fatalf("unexpected cycle path: %v", cycle.Path)
+		}
+	})
+
+	t.Run("block by itself", func(t *testing.T) {
+		var cycle *task.DependencyCycleError
+		if err := svc.Block(ctx, 2, []int{2}); !errors.As(err, &cycle) {
+			t.Fatalf("expected DependencyCycleError, got: %v", err)
+		}
+	})
+
+	t.Run("block without cycle", func(t *testing.T) {
+		if err := svc.Block(ctx, 3, []int{1}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("complete blocked task", func(t *testing.T) {
+		affected, err := svc.Complete(ctx, []int{1, 2}, task.CompleteOptions{})
+		if !errors.Is(err, task.ErrBlocked) {
+			t.Fatalf("expected ErrBlocked, got: %v", err)
+		}
+		if affected != 1 || fmt.Sprint(completed) != "[1]" {
+			t.Fatalf("expected only task 1 completed, got %v", completed)
+		}
+	})
+
+	t.Run("force complete blocked task", func(t *testing.T) {
+		if _, err := svc.Complete(ctx, []int{3}, task.CompleteOptions{Force: true}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if fmt.Sprint(completed) != "[3]" {
+			t.Fatalf("expected task 3 completed, got %v", completed)
+		}
+	})
+}
@@ -2,9 +2,11 @@ package task
 
 import (
 	"context"
+	"slices"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type SqliteRepository struct {
@@ -16,7 +18,14 @@ func NewSqliteRepository(db *gorm.DB) Repository {
 }
 
 func (r *SqliteRepository) Create(ctx context.Context, tasks []Task) error {
-	return r.db.WithContext(ctx).Create(&tasks).Error
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range tasks {
+			if err := resolveTags(tx, tasks[i].Tags); err != nil {
+				return err
+			}
+		}
+		return tx.Create(&tasks).Error
+	})
 }
 
 func (r *SqliteRepository) Delete(ctx context.Context, ids []int) (rowsDeleted int, err error) {
@@ -30,8 +39,9 @@ func (r *SqliteRepository) Delete(ctx context.Context, ids []int) (rowsDeleted i
 	return rowsDeleted, nil
 }
 
-func (r *SqliteRepository) Get(ctx context.Context, ids []int, filter ListFilter) (tasks []Task, err error) {
+func (r *SqliteRepository) Get(ctx context.Context, ids []int, filter ListFilter, opts ListOptions) (tasks []Task, err error) {
 	db := r.db.WithContext(ctx)
+	now := time.Now()
 	switch filter {
 	case IDs:
 		db = db.Where("id IN ?", ids)
@@ -41,10 +51,48 @@ func (r *SqliteRepository) Get(ctx context.Context, ids []int, filter ListFilter
 		db = db.Where("completed_at IS NOT NULL AND deleted_at IS NULL")
 	case Uncompleted:
 		db = db.Where("completed_at IS NULL AND deleted_at IS NULL")
+		if opts.Project == "" {
+			db = db.Where("project_id IS NULL OR project_id NOT IN (?)", archivedProjects(db))
+		}
 	case Removed:
 		db = db.Where("deleted_at IS NOT NULL")
+	case Overdue:
+		db = db.Where("completed_at IS NULL AND deleted_at IS NULL AND due_at < ?", now)
+	case Today:
+		db = db.Where(
+			"completed_at IS NULL AND deleted_at IS NULL AND due_at >= ? AND due_at < ?",
+			startOfDay(now), startOfDay(now).AddDate(0, 0, 1),
+		)
+	case Upcoming:
+		db = db.Where(
+			"completed_at IS NULL AND deleted_at IS NULL AND due_at >= ?",
+			startOfDay(now).AddDate(0, 0, 1),
+		)
+	case Blocked:
+		db = db.Where("completed_at IS NULL AND deleted_at IS NULL AND id IN (?)", blockedTasks(db))
+	case Ready:
+		db = db.Where("completed_at IS NULL AND deleted_at IS NULL AND id NOT IN (?)", blockedTasks(db))
 	}
+	if opts.Project != "" {
+		db = db.Where("project_id IN (?)", newQuery(db).Model(&Project{}).Select("id").Where("name = ?", opts.Project))
+	}
+	if len(opts.Tags) > 0 {
+		db = filterTags(db, opts.Tags, opts.AllTags)
+	}
+	if len(opts.Priorities) > 0 {
+		db = db.Where("priority IN ?", opts.Priorities)
+	}
+	if opts.Where != nil {
+		cond, args := opts.Where.SQL(now)
+		db = db.Where(cond, args...)
+	}
+	db = orderBy(db, opts.Order, opts.Desc)
 
+	db = db.Preload("Project").Preload("Tags", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	}).Preload("Annotations", func(db *gorm.DB) *gorm.DB {
+		return db.Order("created_at, id")
+	})
 	if err = db.Find(&tasks).Error; err != nil {
 		return nil, err
 	}
@@ -52,6 +100,34 @@ func (r *SqliteRepository) Get(ctx context.Context, ids []int, filter ListFilter
 	return tasks, nil
 }
 
+var orderColumns = map[ListOrderValue]string{
+	ByCreated:   "created_at",
+	ByCompleted: "completed_at",
+	ByDeleted:   "deleted_at",
+	ByDue:       "due_at",
+	ByPriority:  "priority",
+}
+
+// orderBy sorts by the given field leaving the NULL values last and
+// breaking ties with the ID, so every backend returns the same order
+func orderBy(db *gorm.DB, order ListOrderValue, desc bool) *gorm.DB {
+	if col, ok := orderColumns[order]; ok {
+		colDesc := desc
+		if order == ByPriority { // most important first
+			colDesc = !desc
+		}
+		db = db.Order(col + " IS NULL").Order(col + direction(colDesc))
+	}
+	return db.Order("id" + direction(desc))
+}
+
+func direction(desc bool) string {
+	if desc {
+		return " DESC"
+	}
+	return " ASC"
+}
+
 func (r *SqliteRepository) Complete(ctx context.Context, ids []int) (rowsCompleted int, err error) {
 	db := gorm.G[Task](r.db)
 
@@ -64,3 +140,347 @@ func (r *SqliteRepository) Complete(ctx context.Context, ids []int) (rowsComplet
 
 	return rowsCompleted, nil
 }
+
+func (r *SqliteRepository) Uncomplete(ctx context.Context, ids []int) (rowsUncompleted int, err error) {
+	rowsUncompleted, err = gorm.G[Task](r.db).
+		Where("id IN ? AND completed_at IS NOT NULL AND deleted_at IS NULL", ids).
+		Update(ctx, "completed_at", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return rowsUncompleted, nil
+}
+
+// Update saves every editable column of a non removed task,
+// returning ErrTaskNotFound when no row matches
+func (r *SqliteRepository) Update(ctx context.Context, task Task) error {
+	res := r.db.WithContext(ctx).
+		Model(&task).
+		Where("deleted_at IS NULL").
+		Select("*").
+		Omit("id", "created_at", "completed_at", "deleted_at", clause.Associations).
+		Updates(&task)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrTaskNotFound
+	}
+
+	return nil
+}
+
+func (r *SqliteRepository) Restore(ctx context.Context, ids []int) (rowsRestored int, err error) {
+	rowsRestored, err = gorm.G[Task](r.db).
+		Where("id IN ? AND deleted_at IS NOT NULL", ids).
+		Update(ctx, "deleted_at", nil)
+	if err != nil {
+		return 0, err
+	}
+
+	return rowsRestored, nil
+}
+
+// Purge removes permanently the given tasks, only if they
+// were already removed before
+func (r *SqliteRepository) Purge(ctx context.Context, ids []int) (rowsPurged int, err error) {
+	err = r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var purged []int
+		err := tx.Model(&Task{}).
+			Where("id IN ? AND deleted_at IS NOT NULL", ids).
+			Pluck("id", &purged).Error
+		if err != nil || len(purged) == 0 {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM task_tags WHERE task_id IN ?", purged).Error; err != nil {
+			return err
+		}
+		err = tx.Where("task_id IN ? OR blocker_id IN ?", purged, purged).Delete(&Dependency{}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("task_id IN ?", purged).Delete(&Annotation{}).Error; err != nil {
+			return err
+		}
+		// the subtasks restored on their own are kept as top level tasks
+		err = tx.Model(&Task{}).Where("parent_id IN ?", purged).Update("parent_id", nil).Error
+		if err != nil {
+			return err
+		}
+		res := tx.Where("id IN ?", purged).Delete(&Task{})
+		rowsPurged = int(res.RowsAffected)
+		return res.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return rowsPurged, nil
+}
+
+// Descendants returns every task below the given ones,
+// at any depth and including the removed ones
+func (r *SqliteRepository) Descendants(ctx context.Context, ids []int) (tasks []Task, err error) {
+	err = r.db.WithContext(ctx).Raw(`
+		WITH RECURSIVE descendants(id) AS (
+			SELECT id FROM tasks WHERE parent_id IN ?
+			UNION
+			SELECT tasks.id FROM tasks JOIN descendants ON tasks.parent_id = descendants.id
+		)
+		SELECT * FROM tasks WHERE id IN (SELECT id FROM descendants) ORDER BY id`, ids,
+	).Scan(&tasks).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tasks, nil
+}
+
+func (r *SqliteRepository) AddDependencies(ctx context.Context, id int, blockers []int) error {
+	deps := make([]Dependency, len(blockers))
+	for i, b := range blockers {
+		deps[i] = Dependency{TaskID: uint(id), BlockerID: uint(b)}
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&deps).Error
+}
+
+func (r *SqliteRepository) RemoveDependencies(ctx context.Context, id int, blockers []int) (rowsRemoved int, err error) {
+	rowsRemoved, err = gorm.G[Dependency](r.db).
+		Where("task_id = ? AND blocker_id IN ?", id, blockers).
+		Delete(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return rowsRemoved, nil
+}
+
+func (r *SqliteRepository) Dependencies(ctx context.Context) (deps []Dependency, err error) {
+	if err = r.db.WithContext(ctx).Order("task_id, blocker_id").Find(&deps).Error; err != nil {
+		return nil, err
+	}
+
+	return deps, nil
+}
+
+func (r *SqliteRepository) AddTags(ctx context.Context, id int, names []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		tags := tagsOf(names)
+		if err := resolveTags(tx, tags); err != nil {
+			return err
+		}
+		return tx.Model(&Task{ID: uint(id)}).Association("Tags").Append(&tags)
+	})
+}
+
+func (r *SqliteRepository) RemoveTags(ctx context.Context, id int, names []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var tags []Tag
+		if err := tx.Where("name IN ?", names).Find(&tags).Error; err != nil || len(tags) == 0 {
+			return err
+		}
+		return tx.Model(&Task{ID: uint(id)}).Association("Tags").Delete(&tags)
+	})
+}
+
+func (r *SqliteRepository) AddAnnotation(ctx context.Context, annotation *Annotation) error {
+	return r.db.WithContext(ctx).Create(annotation).Error
+}
+
+// Tags counts the not removed tasks of every tag, including
+// the tags which are no longer used
+func (r *SqliteRepository) Tags(ctx context.Context) (tags []TagCount, err error) {
+	err = r.db.WithContext(ctx).
+		Table("tags").
+		Select("tags.name AS name, COUNT(tasks.id) AS count").
+		Joins("LEFT JOIN task_tags ON task_tags.tag_id = tags.id").
+		Joins("LEFT JOIN tasks ON tasks.id = task_tags.task_id AND tasks.deleted_at IS NULL").
+		Group("tags.id, tags.name").
+		Order("tags.name").
+		Scan(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// Search returns the tasks of filter matching the query, the best
+// matches first. It uses the FTS5 index when db.Migrate could create
+// it and otherwise scores the tasks found with LIKE
+func (r *SqliteRepository) Search(ctx context.Context, query SearchQuery, filter ListFilter) ([]Task, error) {
+	db := r.db.WithContext(ctx)
+	indexed, err := hasSearchIndex(db)
+	if err != nil {
+		return nil, err
+	}
+	var ranked []uint
+	if indexed {
+		err = db.Table(SearchTable).
+			Select("rowid").
+			Where(SearchTable+" MATCH ?", query.FTS()).
+			Order("bm25(" + SearchTable + ", 2.0, 1.0), rowid").
+			Scan(&ranked).Error
+	} else {
+		ranked, err = likeSearch(db, query)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	tasks, err := r.Get(ctx, nil, filter, ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	rank := make(map[uint]int, len(ranked))
+	for i, id := range ranked {
+		rank[id] = i
+	}
+	tasks = slices.DeleteFunc(tasks, func(t Task) bool {
+		_, ok := rank[t.ID]
+		return !ok
+	})
+	slices.SortFunc(tasks, func(a, b Task) int { return rank[a.ID] - rank[b.ID] })
+	return tasks, nil
+}
+
+// hasSearchIndex reports whether the triggers filling the FTS5 index exist
+func hasSearchIndex(db *gorm.DB) (bool, error) {
+	var count int64
+	err := newQuery(db).
+		Table("sqlite_master").
+		Where("type = 'trigger' AND name = ?", SearchTable+"_insert").
+		Count(&count).Error
+	return count > 0, err
+}
+
+// likeSearch ranks the tasks matching the query without the FTS5 index
+func likeSearch(db *gorm.DB, query SearchQuery) ([]uint, error) {
+	var candidates []Task
+	cond, args := query.likes()
+	err := newQuery(db).
+		Select("id, description, notes").
+		Where(cond, args...).
+		Order("id").
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	type hit struct {
+		id    uint
+		score int
+	}
+	var hits []hit
+	for _, t := range candidates {
+		if score, ok := query.Score(t.Description, t.Notes); ok {
+			hits = append(hits, hit{t.ID, score})
+		}
+	}
+	slices.SortStableFunc(hits, func(a, b hit) int { return b.score - a.score })
+	ids := make([]uint, len(hits))
+	for i, h := range hits {
+		ids[i] = h.id
+	}
+	return ids, nil
+}
+
+// resolveTags fills the IDs of the tags, creating the ones that do not exist yet
+func resolveTags(tx *gorm.DB, tags []Tag) error {
+	for i := range tags {
+		if err := tx.Where(Tag{Name: tags[i].Name}).FirstOrCreate(&tags[i]).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterTags keeps the tasks having any of the tags, or all of them
+func filterTags(db *gorm.DB, tags []string, all bool) *gorm.DB {
+	sub := newQuery(db).
+		Table("task_tags").
+		Select("task_tags.task_id").
+		Joins("JOIN tags ON tags.id = task_tags.tag_id").
+		Where("tags.name IN ?", tags)
+	if all {
+		sub = sub.Group("task_tags.task_id").Having("COUNT(DISTINCT tags.name) = ?", len(tags))
+	}
+	return db.Where("id IN (?)", sub)
+}
+
+func (r *SqliteRepository) CreateProject(ctx context.Context, project *Project) error {
+	return r.db.WithContext(ctx).Create(project).Error
+}
+
+// GetProject finds a project by its name,
+// returning ErrProjectNotFound if there is none
+func (r *SqliteRepository) GetProject(ctx context.Context, name string) (Project, error) {
+	var projects []Project
+	err := r.db.WithContext(ctx).Where("name = ?", name).Limit(1).Find(&projects).Error
+	if err != nil {
+		return Project{}, err
+	}
+	if len(projects) == 0 {
+		return Project{}, ErrProjectNotFound
+	}
+
+	return projects[0], nil
+}
+
+func (r *SqliteRepository) GetProjects(ctx context.Context) (projects []Project, err error) {
+	if err = r.db.WithContext(ctx).Order("name").Find(&projects).Error; err != nil {
+		return nil, err
+	}
+
+	return projects, nil
+}
+
+func (r *SqliteRepository) UpdateProject(ctx context.Context, project Project) error {
+	res := r.db.WithContext(ctx).
+		Model(&project).
+		Select("name", "archived_at").
+		Updates(&project)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return ErrProjectNotFound
+	}
+
+	return nil
+}
+
+// AssignProject moves the given not removed tasks
+// to a project, or out of any project with a nil projectID
+func (r *SqliteRepository) AssignProject(ctx context.Context, ids []int, projectID *uint) (rowsAssigned int, err error) {
+	rowsAssigned, err = gorm.G[Task](r.db).
+		Where("id IN ? AND deleted_at IS NULL", ids).
+		Update(ctx, "project_id", projectID)
+	if err != nil {
+		return 0, err
+	}
+
+	return rowsAssigned, nil
+}
+
+// newQuery starts a query sharing the connection
+// and context of db but none of its conditions
+func newQuery(db *gorm.DB) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true})
+}
+
+// blockedTasks selects the IDs of the tasks with uncompleted blockers
+func blockedTasks(db *gorm.DB) *gorm.DB {
+	return newQuery(db).
+		Table("task_dependencies").
+		Select("task_dependencies.task_id").
+		Joins("JOIN tasks AS blockers ON blockers.id = task_dependencies.blocker_id").
+		Where("blockers.completed_at IS NULL AND blockers.deleted_at IS NULL")
+}
+
+func archivedProjects(db *gorm.DB) *gorm.DB {
+	return newQuery(db).Model(&Project{}).Select("id").Where("archived_at IS NOT NULL")
+}
@@ -2,7 +2,10 @@ package task_test
 
 import (
 	"context"
+	"errors"
+	"slices"
 	"testing"
+	"time"
 
 	"arcedo/cli-todo/internal/db"
 	"arcedo/cli-todo/internal/task"
@@ -83,7 +86,7 @@ func TestSqliteRepository_Delete(t *testing.T) {
 	})
 
 	t.Run("delete all remaining tasks", func(t *testing.T) {
-		tasks, _ := repo.Get(ctx, nil, task.Uncompleted)
+		tasks, _ := repo.Get(ctx, nil, task.Uncompleted, task.ListOptions{})
 		var ids []int
 		for _, tk := range tasks {
 			ids = append(ids, int(tk.ID))
@@ -131,6 +134,39 @@ func TestSqliteRepository_Complete(t *testing.T) {
 	})
 }
 
+func TestSqliteRepository_Uncomplete(t *testing.T) {
+	_, repo := setupRepository(t)
+	ctx := context.Background()
+	tasks := seedTasks(t, repo, "Task A", "Task B")
+	_, _ = repo.Complete(ctx, []int{int(tasks[0].ID)})
+
+	t.Run("uncomplete completed and open tasks", func(t *testing.T) {
+		rows, err := repo.Uncomplete(ctx, []int{int(tasks[0].ID), int(tasks[1].ID)})
+		if err != nil {
+			t.Fatalf("failed to uncomplete tasks: %v", err)
+		}
+		if rows != 1 {
+			t.Errorf("expected 1 row updated (Task A), got %d", rows)
+		}
+		completed, _ := repo.Get(ctx, nil, task.Completed, task.ListOptions{})
+		if len(completed) != 0 {
+			t.Errorf("expected no completed tasks, got %d", len(completed))
+		}
+	})
+
+	t.Run("uncomplete removed task", func(t *testing.T) {
+		_, _ = repo.Complete(ctx, []int{int(tasks[1].ID)})
+		_, _ = repo.Delete(ctx, []int{int(tasks[1].ID)})
+		rows, err := repo.Uncomplete(ctx, []int{int(tasks[1].ID)})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if rows != 0 {
+			t.Errorf("expected 0 rows updated for removed task, got %d", rows)
+		}
+	})
+}
+
 func TestSqliteRepository_Get(t *testing.T) {
 	_, repo := setupRepository(t)
 	ctx := context.Background()
@@ -143,7 +179,7 @@ func TestSqliteRepository_Get(t *testing.T) {
 	}
 
 	t.Run("get all tasks", func(t *testing.T) {
-		all, err := repo.Get(ctx, nil, task.All)
+		all, err := repo.Get(ctx, nil, task.All, task.ListOptions{})
 		if err != nil {
 			t.Fatalf("failed to get all tasks: %v", err)
 		}
@@ -153,7 +189,7 @@ func TestSqliteRepository_Get(t *testing.T) {
 	})
 
 	t.Run("get completed tasks", func(t *testing.T) {
-		completed, err := repo.Get(ctx, nil, task.Completed)
+		completed, err := repo.Get(ctx, nil, task.Completed, task.ListOptions{})
 		if err != nil {
 			t.Fatalf("failed to get completed tasks: %v", err)
 		}
@@ -166,7 +202,7 @@ func TestSqliteRepository_Get(t *testing.T) {
 	})
 
 	t.Run("get uncompleted tasks", func(t *testing.T) {
-		uncompleted, err := repo.Get(ctx, nil, task.Uncompleted)
+		uncompleted, err := repo.Get(ctx, nil, task.Uncompleted, task.ListOptions{})
 		if err != nil {
 			t.Fatalf("failed to get uncompleted tasks: %v", err)
 		}
@@ -176,7 +212,7 @@ func TestSqliteRepository_Get(t *testing.T) {
 	})
 
 	t.Run("get by specific IDs", func(t *testing.T) {
-		specific, err := repo.Get(ctx, []int{int(tasks[1].ID)}, task.IDs)
+		specific, err := repo.Get(ctx, []int{int(tasks[1].ID)}, task.IDs, task.ListOptions{})
 		if err != nil {
 			t.Fatalf("failed to get specific task: %v", err)
 		}
@@ -185,3 +221,622 @@ func TestSqliteRepository_Get(t *testing.T) {
 		}
 	})
 }
+
+func TestSqliteRepository_Update(t *testing.T) {
+	_, repo := setupRepository(t)
+	ctx := context.Background()
+	tasks := seedTasks(t, repo, "Task A", "Task B")
+
+	t.Run("update existing task", func(t *testing.T) {
+		tk := tasks[0]
+		tk.Description = "Task A edited"
+		if err := repo.Update(ctx, tk); err != nil {
+			t.Fatalf("failed to update task: %v", err)
+		}
+		got, _ := repo.Get(ctx, []int{int(tk.ID)}, task.IDs, task.ListOptions{})
+		if len(got) != 1 || got[0].Description != "Task A edited" {
+			t.Errorf("expected edited description, got %v", got)
+		}
+		if !got[0].CreatedAt.Equal(tasks[0].CreatedAt) {
+			t.Errorf("expected CreatedAt to be kept, got %v", got[0].CreatedAt)
+		}
+	})
+
+	t.Run("update removed task", func(t *testing.T) {
+		_, _ = repo.Delete(ctx, []int{int(tasks[1].ID)})
+		err := repo.Update(ctx, tasks[1])
+		if !errors.Is(err, task.ErrTaskNotFound) {
+			t.Errorf("expected ErrTaskNotFound, got %v", err)
+		}
+	})
+}
+
+func TestSqliteRepository_RestoreAndPurge(t *testing.T) {
+	_, repo := setupRepository(t)
+	ctx := context.Background()
+	tasks := seedTasks(t, repo, "Task A", "Task B", "Task C")
+	ids := []int{int(tasks[0].ID), int(tasks[1].ID)}
+	_, _ = repo.Delete(ctx, ids)
+
+	t.Run("restore removed task", func(t *testing.T) {
+		rows, err := repo.Restore(ctx, []int{ids[0], int(tasks[2].ID)})
+		if err != nil {
+			t.Fatalf("failed to restore task: %v", err)
+		}
+		if rows != 1 {
+			t.Errorf("expected 1 row restored, got %d", rows)
+		}
+		removed, _ := repo.Get(ctx, nil, task.Removed, task.ListOptions{})
+		if len(removed) != 1 || removed[0].ID != tasks[1].ID {
+			t.Errorf("expected only task B removed, got %v", removed)
+		}
+	})
+
+	t.Run("purge only removed tasks", func(t *testing.T) {
+		rows, err := repo.Purge(ctx, []int{ids[1], int(tasks[2].ID)})
+		if err != nil {
+			t.Fatalf("failed to purge tasks: %v", err)
+		}
+		if rows != 1 {
+			t.Errorf("expected 1 row purged, got %d", rows)
+		}
+		left, _ := repo.Get(ctx, ids, task.IDs, task.ListOptions{})
+		if len(left) != 1 {
+			t.Errorf("expected purged task to be gone, got %v", left)
+		}
+	})
+}
+
+func TestSqliteRepository_Annotations(t *testing.T) {
+	database, repo := setupRepository(t)
+	ctx := context.Background()
+	tasks := seedTasks(t, repo, "Task A")
+	id := int(tasks[0].ID)
+
+	for _, text := range []string{"first", "second"} {
+		if err := repo.AddAnnotation(ctx, &task.Annotation{TaskID: tasks[0].ID, Text: text}); err != nil {
+			t.Fatalf("failed to add annotation: %v", err)
+		}
+	}
+
+	got, _ := repo.Get(ctx, []int{id}, task.IDs, task.ListOptions{})
+	if len(got) != 1 || len(got[0].Annotations) != 2 || got[0].Annotations[1].Text != "second" {
+		t.Fatalf("expected annotations in order, got %+v", got)
+	}
+
+	_, _ = repo.Delete(ctx, []int{id})
+	if _, err := repo.Purge(ctx, []int{id}); err != nil {
+		t.Fatalf("failed to purge task: %v", err)
+	}
+	var left int64
+	database.Model(&task.Annotation{}).Count(&left)
+	if left != 0 {
+		t.Errorf("expected annotations purged with the task, got %d", left)
+	}
+}
+
+func TestSqliteRepository_GetPriority(t *testing.T) {
+	_, repo := setupRepository(t)
+	ctx := context.Background()
+	tasks := []task.Task{
+		{Description: "Low", Priority: task.Low},
+		{Description: "High", Priority: task.High},
+		{Description: "None"},
+	}
+	if err := repo.Create(ctx, tasks); err != nil {
+		t.Fatalf("failed to seed tasks: %v", err)
+	}
+
+	t.Run("filter by priority", func(t *testing.T) {
+		got, err := repo.Get(ctx, nil, task.All, task.ListOptions{Priorities: []task.Priority{task.High, task.Low}})
+		if err != nil {
+			t.Fatalf("failed to get tasks: %v", err)
+		}
+		if len(got) != 2 {
+			t.Errorf("expected 2 tasks, got %d", len(got))
+		}
+	})
+
+	t.Run("sort by priority", func(t *testing.T) {
+		got, err := repo.Get(ctx, nil, task.All, task.ListOptions{Order: task.ByPriority})
+		if err != nil {
+			t.Fatalf("failed to get tasks: %v", err)
+		}
+		if got[0].Description != "High" || got[1].Description != "Low" || got[2].Description != "None" {
+			t.Errorf("unexpected order: %v", got)
+		}
+	})
+}
+
+func TestSqliteRepository_GetDue(t *testing.T) {
+	_, repo := setupRepository(t)
+	ctx := context.Background()
+	now := time.Now()
+	yesterday := now.AddDate(0, 0, -1)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 0, 0, now.Location())
+	nextWeek := now.AddDate(0, 0, 7)
+	tasks := []task.Task{
+		{Description: "Overdue", DueAt: &yesterday},
+		{Description: "Today", DueAt: &today},
+		{Description: "Next week", DueAt: &nextWeek},
+		{Description: "Done overdue", DueAt: &yesterday, CompletedAt: &now},
+		{Description: "No due"},
+	}
+	if err := repo.Create(ctx, tasks); err != nil {
+		t.Fatalf("failed to seed tasks: %v", err)
+	}
+
+	cases := map[task.ListFilter]string{
+		task.Overdue:  "Overdue",
+		task.Today:    "Today",
+		task.Upcoming: "Next week",
+	}
+	for filter, want := range cases {
+		t.Run(string(filter), func(t *testing.T) {
+			got, err := repo.Get(ctx, nil, filter, task.ListOptions{})
+			if err != nil {
+				t.Fatalf("failed to get tasks: %v", err)
+			}
+			if len(got) != 1 || got[0].Description != want {
+				t.Errorf("expected only %q, got %v", want, got)
+			}
+		})
+	}
+}
+
+func TestSqliteRepository_GetOrder(t *testing.T) {
+	_, repo := setupRepository(t)
+	ctx := context.Background()
+	soon := time.Now().Add(time.Hour)
+	later := time.Now().Add(2 * time.Hour)
+	tasks := []task.Task{
+		{Description: "A", DueAt: &later},
+		{Description: "B"},
+		{Description: "C", DueAt: &soon},
+		{Description: "D"},
+	}
+	if err := repo.Create(ctx, tasks); err != nil {
+		t.Fatalf("failed to seed tasks: %v", err)
+	}
+
+	cases := []struct {
+		name string
+		opts task.ListOptions
+		want string
+	}{
+		{"default by id", task.ListOptions{}, "ABCD"},
+		{"id desc", task.ListOptions{Order: task.ByID, Desc: true}, "DCBA"},
+		{"due nulls last", task.ListOptions{Order: task.ByDue}, "CABD"},
+		{"due desc nulls last", task.ListOptions{Order: task.ByDue, Desc: true}, "ACDB"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := repo.Get(ctx, nil, task.All, tc.opts)
+			if err != nil {
+				t.Fatalf("failed to get tasks: %v", err)
+			}
+			var order string
+			for _, tk := range got {
+				order += tk.Description
+			}
+			if order != tc.want {
+				t.Errorf("expected order %s, got %s", tc.want, order)
+			}
+		})
+	}
+}
+
+func TestSqliteRepository_Tags(t *testing.T) {
+	_, repo := setupRepository(t)
+	ctx := context.Background()
+	tasks := []task.Task{
+		{Description: "A", Tags: []task.Tag{{Name: "work"}, {Name: "urgent"}}},
+		{Description: "B", Tags: []task.Tag{{Name: "work"}}},
+		{Description: "C"},
+	}
+	if err := repo.Create(ctx, tasks); err != nil {
+		t.Fatalf("failed to seed tasks: %v", err)
+	}
+
+	t.Run("filter any tag", func(t *testing.T) {
+		got, _ := repo.Get(ctx, nil, task.All, task.ListOptions{Tags: []string{"work", "urgent"}})
+		if len(got) != 2 {
+			t.Errorf("expected 2 tasks, got %d", len(got))
+		}
+	})
+
+	t.Run("filter all tags", func(t *testing.T) {
+		got, _ := repo.Get(ctx, nil, task.All, task.ListOptions{Tags: []string{"work", "urgent"}, AllTags: true})
+		if len(got) != 1 || got[0].Description != "A" || len(got[0].Tags) != 2 {
+			t.Errorf("expected only A with its tags, got %v", got)
+		}
+	})
+
+	t.Run("add and remove tags", func(t *testing.T) {
+		id := int(tasks[2].ID)
+		if err := repo.AddTags(ctx, id, []string{"home", "work"}); err != nil {
+			t.Fatalf("failed to add tags: %v", err)
+		}
+		if err := repo.RemoveTags(ctx, id, []string{"home", "missing"}); err != nil {
+			t.Fatalf("failed to remove tags: %v", err)
+		}
+		got, _ := repo.Get(ctx, []int{id}, task.IDs, task.ListOptions{})
+		if len(got[0].Tags) != 1 || got[0].Tags[0].Name != "work" {
+			t.Errorf("expected only work tag, got %v", got[0].Tags)
+		}
+	})
+
+	t.Run("count tags", func(t *testing.T) {
+		_, _ = repo.Delete(ctx, []int{int(tasks[1].ID)})
+		got, err := repo.Tags(ctx)
+		if err != nil {
+			t.Fatalf("failed to count tags: %v", err)
+		}
+		want := []task.TagCount{{Name: "home", Count: 0}, {Name: "urgent", Count: 1}, {Name: "work", Count: 2}}
+		if len(got) != len(want) {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("expected %v, got %v", want[i], got[i])
+			}
+		}
+	})
+
+	t.Run("purge removes tag links", func(t *testing.T) {
+		if _, err := repo.Purge(ctx, []int{int(tasks[1].ID)}); err != nil {
+			t.Fatalf("failed to purge: %v", err)
+		}
+		got, _ := repo.Tags(ctx)
+		if got[2].Count != 2 {
+			t.Errorf("expected work to keep 2 tasks, got %v", got[2])
+		}
+	})
+}
+
+func TestSqliteRepository_Projects(t *testing.T) {
+	_, repo := setupRepository(t)
+	ctx := context.Background()
+	home := task.Project{Name: "Home"}
+	work := task.Project{Name: "Work"}
+	for _, p := range []*task.Project{&home, &work} {
+		if err := repo.CreateProject(ctx, p); err != nil {
+			t.Fatalf("failed to create project: %v", err)
+		}
+	}
+	tasks := seedTasks(t, repo, "Paint", "Deploy", "Loose")
+	_, _ = repo.AssignProject(ctx, []int{int(tasks[0].ID)}, &home.ID)
+	_, _ = repo.AssignProject(ctx, []int{int(tasks[1].ID)}, &work.ID)
+
+	t.Run("get project by name", func(t *testing.T) {
+		p, err := repo.GetProject(ctx, "Work")
+		if err != nil || p.ID != work.ID {
+			t.Fatalf("expected Work project, got %v, %v", p, err)
+		}
+		if _, err := repo.GetProject(ctx, "Garden"); !errors.Is(err, task.ErrProjectNotFound) {
+			t.Errorf("expected ErrProjectNotFound, got %v", err)
+		}
+	})
+
+	t.Run("filter by project", func(t *testing.T) {
+		got, _ := repo.Get(ctx, nil, task.All, task.ListOptions{Project: "Home"})
+		if len(got) != 1 || got[0].Project == nil || got[0].Project.Name != "Home" {
+			t.Errorf("expected only Paint in Home, got %v", got)
+		}
+	})
+
+	t.Run("archived project hidden from uncompleted", func(t *testing.T) {
+		now := time.Now()
+		work.ArchivedAt = &now
+		if err := repo.UpdateProject(ctx, work); err != nil {
+			t.Fatalf("failed to archive project: %v", err)
+		}
+		got, _ := repo.Get(ctx, nil, task.Uncompleted, task.ListOptions{})
+		if len(got) != 2 {
+			t.Errorf("expected 2 uncompleted tasks, got %v", got)
+		}
+		got, _ = repo.Get(ctx, nil, task.Uncompleted, task.ListOptions{Project: "Work"})
+		if len(got) != 1 {
+			t.Errorf("expected archived project tasks when asked for, got %v", got)
+		}
+	})
+}
+
+func TestSqliteRepository_Descendants(t *testing.T) {
+	_, repo := setupRepository(t)
+	ctx := context.Background()
+	tasks := seedTasks(t, repo, "Root", "Other")
+	child := task.Task{Description: "Child", ParentID: &tasks[0].ID}
+	if err := repo.Create(ctx, []task.Task{child}); err != nil {
+		t.Fatalf("failed to seed child: %v", err)
+	}
+	got, _ := repo.Get(ctx, nil, task.All, task.ListOptions{})
+	child = got[2]
+	grandchild := task.Task{Description: "Grandchild", ParentID: &child.ID}
+	if err := repo.Create(ctx, []task.Task{grandchild}); err != nil {
+		t.Fatalf("failed to seed grandchild: %v", err)
+	}
+
+	t.Run("every depth", func(t *testing.T) {
+		got, err := repo.Descendants(ctx, []int{int(tasks[0].ID)})
+		if err != nil {
+			t.Fatalf("failed to get descendants: %v", err)
+		}
+		if len(got) != 2 || got[0].Description != "Child" || got[1].Description != "Grandchild" {
+			t.Errorf("expected child and grandchild, got %v", got)
+		}
+	})
+
+	t.Run("purged parent leaves subtasks at the top level", func(t *testing.T) {
+		_, _ = repo.Delete(ctx, []int{int(child.ID)})
+		if _, err := repo.Purge(ctx, []int{int(child.ID)}); err != nil {
+			t.Fatalf("failed to purge: %v", err)
+		}
+		got, _ := repo.Get(ctx, nil, task.All, task.ListOptions{})
+		if len(got) != 3 || got[2].ParentID != nil {
+			t.Errorf("expected grandchild without parent, got %v", got)
+		}
+	})
+}
+
+func TestSqliteRepository_Dependencies(t *testing.T) {
+	_, repo := setupRepository(t)
+	ctx := context.Background()
+	tasks := seedTasks(t, repo, "Build", "Test", "Release")
+	build, test, release := int(tasks[0].ID), int(tasks[1].ID), int(tasks[2].ID)
+
+	if err := repo.AddDependencies(ctx, release, []int{build, test}); err != nil {
+		t.Fatalf("failed to add dependencies: %v", err)
+	}
+	if err := repo.AddDependencies(ctx, release, []int{test}); err != nil {
+		t.Fatalf("failed to add a repeated dependency: %v", err)
+	}
+
+	t.Run("list dependencies", func(t *testing.T) {
+		deps, err := repo.Dependencies(ctx)
+		if err != nil {
+			t.Fatalf("failed to get dependencies: %v", err)
+		}
+		if len(deps) != 2 {
+			t.Errorf("expected 2 dependencies, got %v", deps)
+		}
+	})
+
+	t.Run("blocked and ready", func(t *testing.T) {
+		_, _ = repo.Complete(ctx, []int{build})
+		blocked, _ := repo.Get(ctx, nil, task.Blocked, task.ListOptions{})
+		if len(blocked) != 1 || int(blocked[0].ID) != release {
+			t.Errorf("expected only release blocked, got %v", blocked)
+		}
+		ready, _ := repo.Get(ctx, nil, task.Ready, task.ListOptions{})
+		if len(ready) != 1 || int(ready[0].ID) != test {
+			t.Errorf("expected only test ready, got %v", ready)
+		}
+	})
+
+	t.Run("remove dependencies", func(t *testing.T) {
+		rows, err := repo.RemoveDependencies(ctx, release, []int{test, build})
+		if err != nil {
+			t.Fatalf("failed to remove dependencies: %v", err)
+		}
+		if rows != 2 {
+			t.Errorf("expected 2 dependencies removed, got %d", rows)
+		}
+		ready, _ := repo.Get(ctx, nil, task.Ready, task.ListOptions{})
+		if len(ready) != 2 {
+			t.Errorf("expected 2 ready tasks, got %v", ready)
+		}
+	})
+}
+
+func TestSqliteRepository_CompleteRecurrent(t *testing.T) {
+	database, repo := setupRepository(t)
+	ctx := context.Background()
+	svc := task.NewService(repo)
+	due := time.Date(2026, 1, 29, 9, 0, 0, 0, time.Local)
+	tasks, err := svc.Create(ctx, []string{"Weekly report +work"}, task.CreateOptions{
+		DueAt:      &due,
+		Recurrence: "every week",
+	})
+	if err != nil {
+		t.Fatalf("failed to create task: %v", err)
+	}
+
+	if _, err := svc.Complete(ctx, []int{int(tasks[0].ID)}, task.CompleteOptions{}); err != nil {
+		t.Fatalf("failed to complete task: %v", err)
+	}
+	open, _ := repo.Get(ctx, nil, task.Uncompleted, task.ListOptions{})
+	if len(open) != 1 {
+		t.Fatalf("expected the next occurrence, got %v", open)
+	}
+	next := open[0]
+	if next.Description != "Weekly report" || next.Recurrence != "weekly" {
+		t.Errorf("unexpected next occurrence: %+v", next)
+	}
+	if next.DueAt == nil || !next.DueAt.Equal(due.AddDate(0, 0, 7)) {
+		t.Errorf("expected due a week later, got %v", next.DueAt)
+	}
+	if len(next.Tags) != 1 || next.Tags[0].Name != "work" {
+		t.Errorf("expected tags copied, got %v", next.Tags)
+	}
+
+	t.Run("completing again does not repeat twice", func(t *testing.T) {
+		_, _ = svc.Complete(ctx, []int{int(tasks[0].ID)}, task.CompleteOptions{})
+		var count int64
+		database.Model(&task.Task{}).Count(&count)
+		if count != 2 {
+			t.Errorf("expected 2 tasks, got %d", count)
+		}
+	})
+
+	t.Run("stop recurrence", func(t *testing.T) {
+		stopped, err := svc.StopRecurrence(ctx, int(next.ID))
+		if err != nil || stopped.Recurrence != "" {
+			t.Fatalf("failed to stop recurrence: %v, %+v", err, stopped)
+		}
+		_, _ = svc.Complete(ctx, []int{int(next.ID)}, task.CompleteOptions{})
+		open, _ := repo.Get(ctx, nil, task.Uncompleted, task.ListOptions{})
+		if len(open) != 0 {
+			t.Errorf("expected no more occurrences, got %v", open)
+		}
+	})
+
+	t.Run("recurrent subtask completed with its parent", func(t *testing.T) {
+		parent, err := svc.Create(ctx, []string{"Chores"}, task.CreateOptions{})
+		if err != nil {
+			t.Fatalf("failed to create parent: %v", err)
+		}
+		subtask, err := svc.Create(ctx, []string{"Water the plants"}, task.CreateOptions{
+			DueAt:      &due,
+			Recurrence: "daily",
+			Parent:     int(parent[0].ID),
+		})
+		if err != nil {
+			t.Fatalf("failed to create subtask: %v", err)
+		}
+		if _, err := svc.Complete(ctx, []int{int(parent[0].ID)}, task.CompleteOptions{Recursive: true}); err != nil {
+			t.Fatalf("failed to complete task: %v", err)
+		}
+		open, _ := repo.Get(ctx, nil, task.Uncompleted, task.ListOptions{})
+		found := slices.ContainsFunc(open, func(tk task.Task) bool {
+			return tk.Description == "Water the plants" && tk.ID != subtask[0].ID
+		})
+		if !found {
+			t.Errorf("expected the next occurrence of the subtask, got %v", open)
+		}
+	})
+
+	// occurrences counts the open tasks with the description
+	occurrences := func(desc string) int {
+		open, _ := repo.Get(ctx, nil, task.Uncompleted, task.ListOptions{})
+		return len(slices.DeleteFunc(open, func(tk task.Task) bool { return tk.Description != desc }))
+	}
+
+	t.Run("clock ahead of the database", func(t *testing.T) {
+		svc.SetClock(func() time.Time { return time.Now().Add(48 * time.Hour) })
+		defer svc.SetClock(time.Now)
+		created, err := svc.Create(ctx, []string{"Stretch"}, task.CreateOptions{DueAt: &due, Recurrence: "daily"})
+		if err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		if _, err := svc.Complete(ctx, []int{int(created[0].ID)}, task.CompleteOptions{}); err != nil {
+			t.Fatalf("failed to complete task: %v", err)
+		}
+		if n := occurrences("Stretch"); n != 1 {
+			t.Errorf("expected the next occurrence, got %d", n)
+		}
+	})
+
+	t.Run("reopened and completed again", func(t *testing.T) {
+		created, err := svc.Create(ctx, []string{"Pay rent"}, task.CreateOptions{DueAt: &due, Recurrence: "monthly"})
+		if err != nil {
+			t.Fatalf("failed to create task: %v", err)
+		}
+		id := []int{int(created[0].ID)}
+		if _, err := svc.Complete(ctx, id, task.CompleteOptions{}); err != nil {
+			t.Fatalf("failed to complete task: %v", err)
+		}
+		if _, err := svc.Uncomplete(ctx, id); err != nil {
+			t.Fatalf("failed to reopen task: %v", err)
+		}
+		if n := occurrences("Pay rent"); n != 2 {
+			t.Errorf("expected the reopened task and its next occurrence, got %d", n)
+		}
+		if _, err := svc.Complete(ctx, id, task.CompleteOptions{}); err != nil {
+			t.Fatalf("failed to complete task: %v", err)
+		}
+		if n := occurrences("Pay rent"); n != 1 {
+			t.Errorf("expected a single next occurrence, got %d", n)
+		}
+	})
+}
+
+// testSearch is the contract of Repository.Search, shared by the
+// FTS5 index and the LIKE scan. The index is only used when the
+// tests are built with the sqlite_fts5 tag
+func testSearch(t *testing.T, repo task.Repository) {
+	ctx := context.Background()
+	tasks := []task.Task{
+		{Description: "Buy milk"},
+		{Description: "Call the milkman", Notes: "about the milk delivery"},
+		{Description: "Write report", Notes: "quarterly report\nfor the team"},
+		{Description: "Buy bread"},
+		{Description: "Visit the Café"},
+		{Description: "Buy milk again"},
+	}
+	if err := repo.Create(ctx, tasks); err != nil {
+		t.Fatalf("failed to seed tasks: %v", err)
+	}
+	if _, err := repo.Delete(ctx, []int{6}); err != nil {
+		t.Fatalf("failed to remove a task: %v", err)
+	}
+	if err := repo.Update(ctx, task.Task{ID: 4, Description: "Buy fresh bread", CreatedAt: tasks[3].CreatedAt}); err != nil {
+		t.Fatalf("failed to update a task: %v", err)
+	}
+
+	cases := []struct {
+		query string
+		want  []uint
+		// ordered checks the rank, not only the tasks found
+		ordered bool
+	}{
+		{"milk", []uint{1, 2}, true},
+		{"MILK*", []uint{1, 2}, false},
+		{`"buy milk"`, []uint{1}, true},
+		{"buy OR report", []uint{1, 3, 4}, false},
+		{"buy NOT milk", []uint{4}, true},
+		{"buy AND (bread OR milk)", []uint{1, 4}, false},
+		{"fresh", []uint{4}, true},
+		{"quarterly team", []uint{3}, true},
+		{"café", []uint{5}, true},
+		{"milkmen", nil, true},
+	}
+	for _, c := range cases {
+		query, err := task.ParseSearch(c.query)
+		if err != nil {
+			t.Fatalf("ParseSearch(%q) unexpected error: %v", c.query, err)
+		}
+		found, err := repo.Search(ctx, query, task.All)
+		if err != nil {
+			t.Fatalf("Search(%q) unexpected error: %v", c.query, err)
+		}
+		var got []uint
+		for _, tk := range found {
+			got = append(got, tk.ID)
+		}
+		if !c.ordered {
+			slices.Sort(got)
+		}
+		if !slices.Equal(got, c.want) {
+			t.Errorf("Search(%q) = %v; want %v", c.query, got, c.want)
+		}
+	}
+
+	t.Run("keeps the tasks of the filter", func(t *testing.T) {
+		if _, err := repo.Complete(ctx, []int{4}); err != nil {
+			t.Fatalf("failed to complete a task: %v", err)
+		}
+		query, _ := task.ParseSearch("buy")
+		found, err := repo.Search(ctx, query, task.Completed)
+		if err != nil || len(found) != 1 || found[0].ID != 4 {
+			t.Errorf("expected only the completed task, got %v, %v", found, err)
+		}
+	})
+}
+
+func TestSqliteRepository_Search(t *testing.T) {
+	t.Run("index", func(t *testing.T) {
+		_, repo := setupRepository(t)
+		testSearch(t, repo)
+	})
+
+	t.Run("like", func(t *testing.T) {
+		database, repo := setupRepository(t)
+		for _, name := range []string{"insert", "delete", "update"} {
+			if err := database.Exec("DROP TRIGGER IF EXISTS " + task.SearchTable + "_" + name).Error; err != nil {
+				t.Fatalf("failed to drop the search triggers: %v", err)
+			}
+		}
+		testSearch(t, repo)
+	})
+}
@@ -0,0 +1,74 @@
+package task
+
+import (
+	"errors"
+)
+
+// Subtasks follow their parent on removal: removing, restoring or
+// purging a task does the same to every subtask below it. Completing
+// a parent with open subtasks needs CompleteOptions.Recursive
+
+var (
+	ErrInvalidParent = errors.New("invalid parent")
+	ErrOpenSubtasks  = errors.New("task has open subtasks")
+)
+
+// Progress is the completion roll-up of the subtasks of a task
+type Progress struct {
+	Done  int
+	Total int
+}
+
+// CompleteOptions changes how the tasks are completed
+type CompleteOptions struct {
+	// Recursive completes the open subtasks too. Otherwise
+	// the tasks with open subtasks are not completed
+	Recursive bool
+	// Force completes the tasks even if they are
+	// blocked by tasks that are still open
+	Force bool
+}
+
+// subtree groups the descendants by the
+// root among ids they are below of
+func subtree(ids []int, descendants []Task) map[uint][]Task {
+	children := map[uint][]Task{}
+	for _, t := range descendants {
+		if t.ParentID != nil {
+			children[*t.ParentID] = append(children[*t.ParentID], t)
+		}
+	}
+
+	tree := map[uint][]Task{}
+	for _, id := range ids {
+		root := uint(id)
+		pending := children[root]
+		for len(pending) > 0 {
+			t := pending[0]
+			pending = append(pending[1:], children[t.ID]...)
+			tree[root] = append(tree[root], t)
+		}
+	}
+	return tree
+}
+
+func idsOf(tasks []Task, keep func(t Task) bool) (ids []int) {
+	for _, t := range tasks {
+		if keep(t) {
+			ids = append(ids, int(t.ID))
+		}
+	}
+	return ids
+}
+
+func notRemoved(t Task) bool {
+	return t.DeletedAt == nil
+}
+
+func removed(t Task) bool {
+	return t.DeletedAt != nil
+}
+
+func open(t Task) bool {
+	return t.DeletedAt == nil && t.CompletedAt == nil
+}
@@ -0,0 +1,58 @@
+package task
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type Tag struct {
+	ID   uint   `gorm:"primary_key"`
+	Name string `gorm:"not null;uniqueIndex"`
+}
+
+// TagCount is a tag with the number of
+// not removed tasks that have it
+type TagCount struct {
+	Name  string
+	Count int
+}
+
+var ErrInvalidTag = errors.New("invalid tag")
+
+// tagPrefix marks a word of the description as a tag
+const tagPrefix = "+"
+
+// normalizeTags lowercases the names, removes the optional
+// '+' prefix and the duplicates, keeping the original order
+func normalizeTags(names []string) (tags []string, err error) {
+	seen := map[string]bool{}
+	for _, n := range names {
+		n = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(n), tagPrefix))
+		if err := validateTag(n); err != nil {
+			return nil, err
+		}
+		if seen[n] {
+			continue
+		}
+		seen[n] = true
+		tags = append(tags, n)
+	}
+	return tags, nil
+}
+
+func validateTag(name string) error {
+	if name == "" || strings.IndexFunc(name, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("%w '%s': must be a single non empty word", ErrInvalidTag, name)
+	}
+	return nil
+}
+
+func tagsOf(names []string) []Tag {
+	tags := make([]Tag, len(names))
+	for i, n := range names {
+		tags[i] = Tag{Name: n}
+	}
+	return tags
+}
@@ -0,0 +1,487 @@
+// Package tui is the full-screen terminal interface, built on top of
+// task.Service like the CLI
+package tui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"arcedo/cli-todo/internal/task"
+)
+
+// Options are the preferences of the UI
+type Options struct {
+	// DateFormat is the Go layout of the dates
+	DateFormat string
+	// Filter and Sort are the ones the UI starts with
+	Filter task.ListFilter
+	Sort   task.ListOrderValue
+	// Watch is the path of the database, the tasks are listed
+	// again when it changes
+	Watch string
+}
+
+const helpLine = "a add  e edit  c complete  d delete  R restore  f filter  s sort  / search  r reload  q quit"
+
+// App is the state of the UI. The keys change it through HandleKey
+// and Draw shows it on a Buffer, so it can run on any terminal
+type App struct {
+	svc  *task.Service
+	opts Options
+
+	tasks []task.Task
+	// shown are the indexes in tasks that match the search
+	shown  []int
+	cursor int
+	// offset is the first row of shown in the list
+	offset int
+	filter task.ListFilter
+	sort   task.ListOrderValue
+	desc   bool
+	search string
+
+	// status is the message of the last action
+	status string
+	// prompt is set while the user writes the text of an action
+	prompt *prompt
+}
+
+// prompt reads a line of text, or a single y/n answer when confirm
+type prompt struct {
+	label   string
+	input   []rune
+	confirm bool
+	submit  func(ctx context.Context, text string) error
+}
+
+func New(svc *task.Service, opts Options) *App {
+	if opts.Filter == "" {
+		opts.Filter = task.Uncompleted
+	}
+	if opts.DateFormat == "" {
+		opts.DateFormat = "02/01/2006 15:04"
+	}
+	if opts.Sort == "" {
+		opts.Sort = task.ByID
+	}
+	return &App{svc: svc, opts: opts, filter: opts.Filter, sort: opts.Sort}
+}
+
+// Reload lists the tasks again, keeping the selected one if it is still listed
+func (a *App) Reload(ctx context.Context) error {
+	var selected uint
+	if t, ok := a.selected(); ok {
+		selected = t.ID
+	}
+	tasks, err := a.svc.List(ctx, nil, a.filter, task.ListOptions{Order: a.sort, Desc: a.desc})
+	if err != nil {
+		return err
+	}
+	a.tasks = tasks
+	a.applySearch()
+	for i, idx := range a.shown {
+		if a.tasks[idx].ID == selected {
+			a.cursor = i
+		}
+	}
+	a.cursor = max(0, min(a.cursor, len(a.shown)-1))
+	return nil
+}
+
+func (a *App) applySearch() {
+	a.shown = a.shown[:0]
+	query := strings.ToLower(a.search)
+	for i, t := range a.tasks {
+		if query == "" || strings.Contains(strings.ToLower(t.Description), query) {
+			a.shown = append(a.shown, i)
+		}
+	}
+}
+
+func (a *App) selected() (task.Task, bool) {
+	if a.cursor < 0 || a.cursor >= len(a.shown) {
+		return task.Task{}, false
+	}
+	return a.tasks[a.shown[a.cursor]], true
+}
+
+// HandleKey applies key and reports whether the user quits
+func (a *App) HandleKey(ctx context.Context, key Key) (quit bool) {
+	if key.Code == KeyCtrlC {
+		return true
+	}
+	if a.prompt != nil {
+		a.promptKey(ctx, key)
+		return false
+	}
+
+	switch key.Code {
+	case KeyUp:
+		a.move(-1)
+	case KeyDown:
+		a.move(1)
+	case KeyPageUp:
+		a.move(-10)
+	case KeyPageDown:
+		a.move(10)
+	case KeyHome:
+		a.move(-len(a.shown))
+	case KeyEnd:
+		a.move(len(a.shown))
+	case KeyEsc:
+		if a.search != "" {
+			a.search = ""
+			a.status = ""
+			a.reload(ctx)
+		}
+	case KeyRune:
+		return a.command(ctx, key.Rune)
+	}
+	return false
+}
+
+func (a *App) command(ctx context.Context, r rune) (quit bool) {
+	t, ok := a.selected()
+	switch r {
+	case 'q':
+		return true
+	case 'j':
+		a.move(1)
+	case 'k':
+		a.move(-1)
+	case 'g':
+		a.move(-len(a.shown))
+	case 'G':
+		a.move(len(a.shown))
+	case 'a':
+		a.ask("New task: ", "", func(ctx context.Context, text string) error {
+			_, err := a.svc.Create(ctx, []string{text}, task.CreateOptions{})
+			return err
+		})
+	case 'e':
+		if ok {
+			a.ask(fmt.Sprintf("Edit task %d: ", t.ID), t.Description, func(ctx context.Context, text string) error {
+				_, err := a.svc.Edit(ctx, int(t.ID), text)
+				return err
+			})
+		}
+	case 'c', ' ':
+		if ok {
+			a.toggle(ctx, t)
+		}
+	case 'd':
+		if ok && t.DeletedAt == nil {
+			a.prompt = &prompt{
+				label:   fmt.Sprintf("Delete task %d? (y/n) ", t.ID),
+				confirm: true,
+				submit: func(ctx context.Context, text string) error {
+					_, err := a.svc.Delete(ctx, []int{int(t.ID)})
+					return err
+				},
+			}
+		}
+	case 'R':
+		if ok && t.DeletedAt != nil {
+			a.do(ctx, func() error {
+				_, err := a.svc.Restore(ctx, []int{int(t.ID)})
+				return err
+			})
+		}
+	case 'f':
+		a.filter = next(task.ListFilters, a.filter)
+		a.reload(ctx)
+	case 's':
+		a.sort = next(task.ListOrderValues, a.sort)
+		a.reload(ctx)
+	case 'S':
+		a.desc = !a.desc
+		a.reload(ctx)
+	case '/':
+		a.ask("Search: ", a.search, func(ctx context.Context, text string) error {
+			a.search = text
+			return nil
+		})
+	case 'r':
+		a.reload(ctx)
+	case '?':
+		a.status = helpLine
+	}
+	return false
+}
+
+// toggle completes t, or reopens it when it is completed
+func (a *App) toggle(ctx context.Context, t task.Task) {
+	a.do(ctx, func() error {
+		var err error
+		if t.CompletedAt != nil {
+			_, err = a.svc.Uncomplete(ctx, []int{int(t.ID)})
+		} else {
+			_, err = a.svc.Complete(ctx, []int{int(t.ID)}, task.CompleteOptions{})
+		}
+		return err
+	})
+}
+
+// do runs an action and lists the tasks again, the error
+// of the action is shown in the status line
+func (a *App) do(ctx context.Context, action func() error) {
+	a.status = ""
+	if err := action(); err != nil {
+		a.status = err.Error()
+	}
+	if err := a.Reload(ctx); err != nil {
+		a.status = err.Error()
+	}
+}
+
+func (a *App) reload(ctx context.Context) {
+	a.do(ctx, func() error { return nil })
+}
+
+func (a *App) ask(label, text string, submit func(ctx context.Context, text string) error) {
+	a.prompt = &prompt{label: label, input: []rune(text), submit: submit}
+}
+
+func (a *App) promptKey(ctx context.Context, key Key) {
+	p := a.prompt
+	if p.confirm {
+		a.prompt = nil
+		if key.Code == KeyRune && (key.Rune == 'y' || key.Rune == 'Y') {
+			a.do(ctx, func() error { return p.submit(ctx, "") })
+		}
+		return
+	}
+	switch key.Code {
+	case KeyEsc:
+		a.prompt = nil
+	case KeyEnter:
+		a.prompt = nil
+		a.do(ctx, func() error { return p.submit(ctx, string(p.input)) })
+	case KeyBackspace:
+		if len(p.input) > 0 {
+			p.input = p.input[:len(p.input)-1]
+		}
+	case KeyRune:
+		p.input = append(p.input, key.Rune)
+	}
+}
+
+func (a *App) move(n int) {
+	a.cursor = max(0, min(a.cursor+n, len(a.shown)-1))
+}
+
+// next returns the value after current in values, the first after the last
+func next[T comparable](values []T, current T) T {
+	i := slices.Index(values, current)
+	return values[(i+1)%len(values)]
+}
+
+// Draw shows the UI on b: a header, the list of tasks with the detail
+// of the selected one on the right when there is room, and the status
+// line at the bottom
+func (a *App) Draw(b *Buffer) {
+	b.Clear()
+	width, height := b.Size()
+	if width == 0 || height == 0 {
+		return
+	}
+
+	header := fmt.Sprintf(" cli-todo  filter: %s  sort: %s", a.filter, a.sortName())
+	if a.search != "" {
+		header += fmt.Sprintf("  search: %q", a.search)
+	}
+	header += fmt.Sprintf("  %d tasks", len(a.shown))
+	b.SetString(0, 0, width, header, reverse)
+	b.Fill(0, 0, width, reverse)
+
+	listWidth := width
+	if width >= 60 {
+		listWidth = width * 3 / 5
+		for y := 1; y < height-1; y++ {
+			b.SetString(listWidth, y, 1, "│", dim)
+		}
+		a.drawDetail(b, listWidth+2, 1, width-listWidth-2, height-2)
+	}
+	a.drawList(b, 0, 1, listWidth, height-2)
+	a.drawStatus(b, height-1, width)
+}
+
+func (a *App) sortName() string {
+	name := string(a.sort)
+	if a.desc {
+		name += " desc"
+	}
+	return name
+}
+
+func (a *App) drawList(b *Buffer, x, y, width, height int) {
+	if len(a.shown) == 0 {
+		b.SetString(x+1, y, width-1, "No tasks found", dim)
+		return
+	}
+	if height <= 0 {
+		return
+	}
+	// scroll to keep the cursor in view
+	a.offset = min(a.offset, a.cursor)
+	if a.cursor >= a.offset+height {
+		a.offset = a.cursor - height + 1
+	}
+
+	now := time.Now()
+	for row := 0; row < height && a.offset+row < len(a.shown); row++ {
+		i := a.offset + row
+		t := a.tasks[a.shown[i]]
+		st := plain
+		switch {
+		case i == a.cursor:
+			st = reverse
+		case t.CompletedAt != nil || t.DeletedAt != nil:
+			st = dim
+		case t.Overdue(now):
+			st = bold
+		}
+		b.SetString(x, y+row, width, " "+taskLine(t), st)
+		if i == a.cursor {
+			b.Fill(x, y+row, width, reverse)
+		}
+	}
+}
+
+// taskLine is the row of t in the list
+func taskLine(t task.Task) string {
+	check := "[ ]"
+	if t.CompletedAt != nil {
+		check = "[x]"
+	}
+	line := fmt.Sprintf("%s %3d ", check, t.ID)
+	if t.Priority != task.NoPriority {
+		line += "!" + t.Priority.String() + " "
+	}
+	line += t.Description
+	for _, tag := range t.Tags {
+		line += " +" + tag.Name
+	}
+	return line
+}
+
+func (a *App) drawDetail(b *Buffer, x, y, width, height int) {
+	t, ok := a.selected()
+	if !ok || width <= 0 {
+		return
+	}
+	var lines []string
+	field := func(name, value string) {
+		if value != "" {
+			lines = append(lines, name+": "+value)
+		}
+	}
+	date := func(at *time.Time) string {
+		if at == nil {
+			return ""
+		}
+		return at.Format(a.opts.DateFormat)
+	}
+	field("ID", strconv.Itoa(int(t.ID)))
+	lines = append(lines, wrap(t.Description, width)...)
+	lines = append(lines, "")
+	status := "open"
+	switch {
+	case t.DeletedAt != nil:
+		status = "removed"
+	case t.CompletedAt != nil:
+		status = "completed"
+	}
+	field("Status", status)
+	field("Priority", t.Priority.String())
+	field("Due", date(t.DueAt))
+	if t.Project != nil {
+		field("Project", t.Project.Name)
+	}
+	if t.Context != "" {
+		field("Context", "@"+t.Context)
+	}
+	var tags []string
+	for _, tag := range t.Tags {
+		tags = append(tags, "+"+tag.Name)
+	}
+	field("Tags", strings.Join(tags, " "))
+	field("Repeat", t.Recurrence)
+	field("Created", date(&t.CreatedAt))
+	field("Completed", date(t.CompletedAt))
+	if t.Notes != "" {
+		lines = append(lines, "", "Notes:")
+		for _, line := range strings.Split(t.Notes, "\n") {
+			lines = append(lines, wrap(line, width)...)
+		}
+	}
+	if len(t.Annotations) > 0 {
+		lines = append(lines, "", "Annotations:")
+		for _, an := range t.Annotations {
+			lines = append(lines, wrap(an.CreatedAt.Format(a.opts.DateFormat)+" "+an.Text, width)...)
+		}
+	}
+
+	for i, line := range lines {
+		if i >= height {
+			break
+		}
+		st := plain
+		if i == 1 {
+			st = bold
+		}
+		b.SetString(x, y+i, width, line, st)
+	}
+}
+
+func (a *App) drawStatus(b *Buffer, y, width int) {
+	if p := a.prompt; p != nil {
+		text := p.label + string(p.input)
+		if !p.confirm {
+			text += "_"
+		}
+		// keep the end of a long input in view
+		if r := []rune(text); len(r) > width {
+			text = string(r[len(r)-width:])
+		}
+		b.SetString(0, y, width, text, bold)
+		return
+	}
+	if a.status != "" {
+		b.SetString(0, y, width, a.status, bold)
+		return
+	}
+	b.SetString(0, y, width, "? help  q quit", dim)
+}
+
+// wrap splits s in lines of at most width runes, at the spaces if possible
+func wrap(s string, width int) []string {
+	var lines []string
+	var line []rune
+	for _, word := range strings.Fields(s) {
+		w := []rune(word)
+		for len(w) > width {
+			if len(line) > 0 {
+				lines = append(lines, string(line))
+				line = nil
+			}
+			lines, w = append(lines, string(w[:width])), w[width:]
+		}
+		if len(line) > 0 && len(line)+1+len(w) > width {
+			lines = append(lines, string(line))
+			line = nil
+		}
+		if len(line) > 0 {
+			line = append(line, ' ')
+		}
+		line = append(line, w...)
+	}
+	if len(line) > 0 || len(lines) == 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
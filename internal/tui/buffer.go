@@ -0,0 +1,119 @@
+package tui
+
+import (
+	"io"
+	"strings"
+)
+
+// style is the look of a cell
+type style uint8
+
+const (
+	plain style = iota
+	bold
+	dim
+	reverse
+)
+
+var styleCodes = map[style]string{
+	plain:   "\033[0m",
+	bold:    "\033[0;1m",
+	dim:     "\033[0;2m",
+	reverse: "\033[0;7m",
+}
+
+type cell struct {
+	r     rune
+	style style
+}
+
+// Buffer is a virtual terminal, a grid of cells the UI draws on.
+// It is written to the real terminal by Render and read by the
+// tests with Lines
+type Buffer struct {
+	width, height int
+	cells         []cell
+}
+
+func NewBuffer(width, height int) *Buffer {
+	b := &Buffer{width: max(width, 0), height: max(height, 0)}
+	b.cells = make([]cell, b.width*b.height)
+	b.Clear()
+	return b
+}
+
+func (b *Buffer) Size() (width, height int) {
+	return b.width, b.height
+}
+
+func (b *Buffer) Clear() {
+	for i := range b.cells {
+		b.cells[i] = cell{' ', plain}
+	}
+}
+
+// SetString writes s from x, y with style, cutting it at width
+// cells or at the edge. It returns the number of cells written
+func (b *Buffer) SetString(x, y, width int, s string, st style) int {
+	if y < 0 || y >= b.height {
+		return 0
+	}
+	n := 0
+	for _, r := range s {
+		if x+n >= b.width || n >= width {
+			break
+		}
+		if r < ' ' {
+			r = ' '
+		}
+		b.cells[y*b.width+x+n] = cell{r, st}
+		n++
+	}
+	return n
+}
+
+// Fill sets the style of the cells from x to x+width of the line y
+func (b *Buffer) Fill(x, y, width int, st style) {
+	if y < 0 || y >= b.height {
+		return
+	}
+	for i := x; i < min(x+width, b.width); i++ {
+		b.cells[y*b.width+i].style = st
+	}
+}
+
+// Lines returns the text of each line without the styles
+// nor the trailing spaces
+func (b *Buffer) Lines() []string {
+	lines := make([]string, b.height)
+	for y := range lines {
+		var sb strings.Builder
+		for _, c := range b.cells[y*b.width : (y+1)*b.width] {
+			sb.WriteRune(c.r)
+		}
+		lines[y] = strings.TrimRight(sb.String(), " ")
+	}
+	return lines
+}
+
+// Render draws the whole buffer on a terminal from its top left corner
+func (b *Buffer) Render(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("\033[H")
+	for y := 0; y < b.height; y++ {
+		current := style(255)
+		for _, c := range b.cells[y*b.width : (y+1)*b.width] {
+			if c.style != current {
+				sb.WriteString(styleCodes[c.style])
+				current = c.style
+			}
+			sb.WriteRune(c.r)
+		}
+		sb.WriteString(styleCodes[plain])
+		if y < b.height-1 {
+			sb.WriteString("\r\n")
+		}
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
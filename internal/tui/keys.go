@@ -0,0 +1,96 @@
+package tui
+
+import "unicode/utf8"
+
+// KeyCode is a key without a printable rune
+type KeyCode int
+
+const (
+	KeyRune KeyCode = iota
+	KeyEnter
+	KeyEsc
+	KeyBackspace
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyHome
+	KeyEnd
+	KeyPageUp
+	KeyPageDown
+	KeyCtrlC
+)
+
+// Key is a key pressed by the user, Rune is set for KeyRune
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// Rune returns the key of a printable rune
+func Rune(r rune) Key {
+	return Key{Code: KeyRune, Rune: r}
+}
+
+var escapes = map[string]KeyCode{
+	"\033[A":  KeyUp,
+	"\033[B":  KeyDown,
+	"\033[C":  KeyRight,
+	"\033[D":  KeyLeft,
+	"\033OA":  KeyUp,
+	"\033OB":  KeyDown,
+	"\033OC":  KeyRight,
+	"\033OD":  KeyLeft,
+	"\033[H":  KeyHome,
+	"\033[F":  KeyEnd,
+	"\033[1~": KeyHome,
+	"\033[4~": KeyEnd,
+	"\033[5~": KeyPageUp,
+	"\033[6~": KeyPageDown,
+}
+
+// decodeKeys decodes the bytes read from a terminal in raw mode.
+// The unknown escape sequences are dropped
+func decodeKeys(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		switch c := b[0]; {
+		case c == '\033':
+			n, code := escape(b)
+			if code != KeyRune {
+				keys = append(keys, Key{Code: code})
+			}
+			b = b[n:]
+			continue
+		case c == '\r' || c == '\n':
+			keys = append(keys, Key{Code: KeyEnter})
+		case c == 127 || c == '\b':
+			keys = append(keys, Key{Code: KeyBackspace})
+		case c == 3:
+			keys = append(keys, Key{Code: KeyCtrlC})
+		case c < ' ':
+		default:
+			r, n := utf8.DecodeRune(b)
+			keys = append(keys, Rune(r))
+			b = b[n:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// escape returns the length of the escape sequence at the start of b
+// and its key, KeyRune when it is unknown. A lone ESC is KeyEsc
+func escape(b []byte) (int, KeyCode) {
+	if len(b) == 1 || b[1] != '[' && b[1] != 'O' {
+		return 1, KeyEsc
+	}
+	// the sequence ends at the first letter or ~ after ESC [
+	for i := 2; i < len(b); i++ {
+		if c := b[i]; c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c == '~' {
+			return i + 1, escapes[string(b[:i+1])]
+		}
+	}
+	return len(b), KeyRune
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package tui
+
+import "os"
+
+// resizes never signals, as there is no resize signal on this system
+func resizes() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
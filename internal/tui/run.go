@@ -0,0 +1,158 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+
+	"arcedo/cli-todo/internal/task"
+)
+
+// watchInterval is how often the database file is checked for changes
+const watchInterval = 500 * time.Millisecond
+
+var ErrNotTerminal = errors.New("not a terminal")
+
+// Run shows the UI on the terminal of in and out until the user quits.
+// The tasks are listed again when the file at opts.Watch changes, so
+// the changes made from another terminal are shown
+func Run(ctx context.Context, svc *task.Service, in, out *os.File, opts Options) error {
+	if !term.IsTerminal(int(in.Fd())) || !term.IsTerminal(int(out.Fd())) {
+		return fmt.Errorf("failed to start the UI: %w", ErrNotTerminal)
+	}
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return fmt.Errorf("failed to start the UI: %w", err)
+	}
+	defer term.Restore(int(in.Fd()), state)
+	// the alternate screen keeps the shell output as it was
+	io.WriteString(out, "\033[?1049h\033[?25l")
+	defer io.WriteString(out, "\033[?25h\033[?1049l")
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	app := New(svc, opts)
+	size := func() (int, int) {
+		w, h, err := term.GetSize(int(out.Fd()))
+		if err != nil {
+			return 80, 24
+		}
+		return w, h
+	}
+	resize, stop := resizes()
+	defer stop()
+	return loop(ctx, app, size, events{
+		keys:    readKeys(ctx, in),
+		resize:  resize,
+		changes: watch(ctx, opts.Watch, watchInterval),
+	}, func(b *Buffer) error {
+		return b.Render(out)
+	})
+}
+
+// events are what the UI reacts to besides the keys
+type events struct {
+	keys    <-chan Key
+	resize  <-chan os.Signal
+	changes <-chan struct{}
+}
+
+// loop draws the UI and updates it on each event until the user quits,
+// the keys channel is closed or ctx is done
+func loop(ctx context.Context, app *App, size func() (width, height int), ev events, draw func(*Buffer) error) error {
+	buf := NewBuffer(size())
+	redraw := func() error {
+		app.Draw(buf)
+		return draw(buf)
+	}
+	if err := app.Reload(ctx); err != nil {
+		return err
+	}
+	if err := redraw(); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case key, ok := <-ev.keys:
+			if !ok || app.HandleKey(ctx, key) {
+				return nil
+			}
+		case <-ev.resize:
+			buf = NewBuffer(size())
+		case <-ev.changes:
+			if err := app.Reload(ctx); err != nil {
+				app.status = err.Error()
+			}
+		}
+		if err := redraw(); err != nil {
+			return err
+		}
+	}
+}
+
+// readKeys sends the keys read from in until it fails or ctx is done
+func readKeys(ctx context.Context, in io.Reader) <-chan Key {
+	keys := make(chan Key)
+	go func() {
+		defer close(keys)
+		b := make([]byte, 256)
+		for {
+			n, err := in.Read(b)
+			for _, key := range decodeKeys(b[:n]) {
+				select {
+				case keys <- key:
+				case <-ctx.Done():
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return keys
+}
+
+// watch signals when the file at path, or its write-ahead log, changes.
+// It checks the size and the modification time every interval
+func watch(ctx context.Context, path string, interval time.Duration) <-chan struct{} {
+	changes := make(chan struct{}, 1)
+	if path == "" || path == ":memory:" {
+		return changes
+	}
+	stat := func() (state [2]string) {
+		for i, p := range []string{path, path + "-wal"} {
+			if info, err := os.Stat(p); err == nil {
+				state[i] = fmt.Sprint(info.Size(), info.ModTime().UnixNano())
+			}
+		}
+		return state
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		last := stat()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if state := stat(); state != last {
+				last = state
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return changes
+}
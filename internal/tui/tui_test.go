@@ -0,0 +1,260 @@
+package tui
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"arcedo/cli-todo/internal/db"
+	"arcedo/cli-todo/internal/task"
+)
+
+func setupApp(t *testing.T, descriptions ...string) (*App, *task.Service) {
+	database, err := db.ConnectSqlite(":memory:")
+	if err != nil {
+		t.Fatalf("failed to connect to sqlite: %v", err)
+	}
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("failed to migrate schema: %v", err)
+	}
+	svc := task.NewService(task.NewSqliteRepository(database))
+	if len(descriptions) > 0 {
+		if _, err := svc.Create(context.Background(), descriptions, task.CreateOptions{}); err != nil {
+			t.Fatalf("failed to seed tasks: %v", err)
+		}
+	}
+	app := New(svc, Options{})
+	if err := app.Reload(context.Background()); err != nil {
+		t.Fatalf("failed to load the tasks: %v", err)
+	}
+	return app, svc
+}
+
+// press sends keys to app, the runes of a string are typed one by one
+func press(app *App, keys ...any) (quit bool) {
+	for _, k := range keys {
+		switch k := k.(type) {
+		case Key:
+			quit = app.HandleKey(context.Background(), k)
+		case string:
+			for _, r := range k {
+				quit = app.HandleKey(context.Background(), Rune(r))
+			}
+		}
+	}
+	return quit
+}
+
+func screen(app *App, width, height int) string {
+	b := NewBuffer(width, height)
+	app.Draw(b)
+	return strings.Join(b.Lines(), "\n")
+}
+
+func TestApp_Draw(t *testing.T) {
+	app, _ := setupApp(t, "Buy milk +shop", "Call mom")
+
+	got := screen(app, 80, 10)
+	for _, want := range []string{
+		"filter: uncompleted  sort: id  2 tasks",
+		"[ ]   1 Buy milk +shop",
+		"[ ]   2 Call mom",
+		"│ Buy milk",
+		"Tags: +shop",
+		"? help  q quit",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected the screen to contain %q, got:\n%s", want, got)
+		}
+	}
+
+	// without room for the detail pane only the list is shown
+	if got := screen(app, 40, 6); strings.Contains(got, "│") {
+		t.Errorf("expected no detail pane on a narrow screen, got:\n%s", got)
+	}
+}
+
+func TestApp_Scroll(t *testing.T) {
+	app, _ := setupApp(t, "T1", "T2", "T3", "T4", "T5")
+
+	press(app, "jjjj")
+	lines := strings.Split(screen(app, 40, 4), "\n")
+	if !strings.Contains(lines[1], "T4") || !strings.Contains(lines[2], "T5") {
+		t.Errorf("expected the list to scroll to the cursor, got:\n%s", strings.Join(lines, "\n"))
+	}
+	press(app, "g")
+	if lines := strings.Split(screen(app, 40, 4), "\n"); !strings.Contains(lines[1], "T1") {
+		t.Errorf("expected the list to scroll back to the top, got:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestApp_Actions(t *testing.T) {
+	ctx := context.Background()
+	app, svc := setupApp(t, "Buy milk", "Call mom")
+
+	t.Run("adds a task", func(t *testing.T) {
+		press(app, "a", "Walk the dog", Key{Code: KeyEnter})
+		tasks, _ := svc.List(ctx, nil, task.All, task.ListOptions{})
+		if len(tasks) != 3 || tasks[2].Description != "Walk the dog" {
+			t.Fatalf("expected the task to be added, got %v", tasks)
+		}
+	})
+
+	t.Run("cancels a prompt", func(t *testing.T) {
+		press(app, "a", "Nothing", Key{Code: KeyEsc})
+		tasks, _ := svc.List(ctx, nil, task.All, task.ListOptions{})
+		if len(tasks) != 3 {
+			t.Fatalf("expected no task to be added, got %d tasks", len(tasks))
+		}
+	})
+
+	t.Run("edits the selected task", func(t *testing.T) {
+		press(app, "g", "e", Key{Code: KeyBackspace}, Key{Code: KeyBackspace}, Key{Code: KeyBackspace}, Key{Code: KeyBackspace}, "bread", Key{Code: KeyEnter})
+		got, _ := svc.Show(ctx, 1)
+		if got.Description != "Buy bread" {
+			t.Errorf("expected 'Buy bread', got %q", got.Description)
+		}
+	})
+
+	t.Run("completes the selected task", func(t *testing.T) {
+		press(app, "c")
+		got, _ := svc.Show(ctx, 1)
+		if got.CompletedAt == nil {
+			t.Error("expected the task to be completed")
+		}
+		if s := screen(app, 80, 6); strings.Contains(s, "Buy bread") {
+			t.Errorf("expected the completed task to leave the list, got:\n%s", s)
+		}
+	})
+
+	t.Run("deletes after confirming", func(t *testing.T) {
+		press(app, "g", "d", "n")
+		if got, _ := svc.Show(ctx, 2); got.DeletedAt != nil {
+			t.Fatal("expected the task not to be deleted when answering n")
+		}
+		press(app, "d")
+		if s := screen(app, 80, 6); !strings.Contains(s, "Delete task 2? (y/n)") {
+			t.Errorf("expected the confirmation prompt, got:\n%s", s)
+		}
+		press(app, "y")
+		if got, _ := svc.Show(ctx, 2); got.DeletedAt == nil {
+			t.Error("expected the task to be deleted")
+		}
+	})
+
+	t.Run("shows the errors in the status line", func(t *testing.T) {
+		press(app, "a", Key{Code: KeyEnter})
+		if s := screen(app, 80, 6); !strings.Contains(s, "validation errors") {
+			t.Errorf("expected the error in the status line, got:\n%s", s)
+		}
+	})
+
+	t.Run("quits", func(t *testing.T) {
+		if !press(app, "q") {
+			t.Error("expected q to quit")
+		}
+		if !press(app, Key{Code: KeyCtrlC}) {
+			t.Error("expected ctrl-c to quit")
+		}
+	})
+}
+
+func TestApp_FilterSortSearch(t *testing.T) {
+	ctx := context.Background()
+	app, svc := setupApp(t, "Buy milk", "Call mom", "Buy bread")
+	svc.Complete(ctx, []int{2}, task.CompleteOptions{})
+	app.Reload(ctx)
+
+	press(app, "f")
+	if s := screen(app, 80, 6); !strings.Contains(s, "filter: completed  sort: id  1 tasks") {
+		t.Errorf("expected the completed filter, got:\n%s", s)
+	}
+
+	press(app, "f", "f", "f", "f", "f", "f", "f", "f")
+	if app.filter != task.Uncompleted {
+		t.Errorf("expected the filter to cycle back to uncompleted, got %s", app.filter)
+	}
+
+	press(app, "s", "S")
+	if s := screen(app, 80, 6); !strings.Contains(s, "sort: created desc") {
+		t.Errorf("expected the sort by created desc, got:\n%s", s)
+	}
+
+	press(app, "/", "BREAD", Key{Code: KeyEnter})
+	s := screen(app, 80, 6)
+	if !strings.Contains(s, `search: "BREAD"  1 tasks`) || strings.Contains(s, "milk") {
+		t.Errorf("expected only the matching task, got:\n%s", s)
+	}
+	press(app, Key{Code: KeyEsc})
+	if s := screen(app, 80, 6); !strings.Contains(s, "2 tasks") {
+		t.Errorf("expected Esc to clear the search, got:\n%s", s)
+	}
+}
+
+func TestDecodeKeys(t *testing.T) {
+	got := decodeKeys([]byte("aé\r\x7f\x1b[A\x1bOB\x1b[5~\x1b[99X\x1b\x03"))
+	want := []Key{
+		Rune('a'), Rune('é'), {Code: KeyEnter}, {Code: KeyBackspace},
+		{Code: KeyUp}, {Code: KeyDown}, {Code: KeyPageUp}, {Code: KeyEsc}, {Code: KeyCtrlC},
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestLoop(t *testing.T) {
+	app, svc := setupApp(t, "Buy milk")
+	keys := make(chan Key)
+	resize := make(chan os.Signal)
+	changes := make(chan struct{})
+	width := 80
+	frames := make(chan *Buffer)
+	done := make(chan error)
+	go func() {
+		done <- loop(context.Background(), app, func() (int, int) { return width, 5 }, events{keys, resize, changes}, func(b *Buffer) error {
+			frames <- b
+			return nil
+		})
+	}()
+
+	if w, _ := (<-frames).Size(); w != 80 {
+		t.Errorf("expected a first frame 80 wide, got %d", w)
+	}
+
+	width = 40
+	resize <- os.Interrupt
+	if w, _ := (<-frames).Size(); w != 40 {
+		t.Errorf("expected the frame to follow the resize, got %d wide", w)
+	}
+
+	svc.Create(context.Background(), []string{"Call mom"}, task.CreateOptions{})
+	changes <- struct{}{}
+	if s := strings.Join((<-frames).Lines(), "\n"); !strings.Contains(s, "Call mom") {
+		t.Errorf("expected the change to be shown, got:\n%s", s)
+	}
+
+	keys <- Rune('q')
+	if err := <-done; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.db")
+	os.WriteFile(path, []byte("a"), 0o644)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes := watch(ctx, path, 10*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	os.WriteFile(path, []byte("ab"), 0o644)
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Error("expected the change of the file to be signaled")
+	}
+}
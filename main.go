@@ -6,22 +6,41 @@ import (
 	"os"
 
 	"arcedo/cli-todo/internal/cli"
+	"arcedo/cli-todo/internal/config"
 	"arcedo/cli-todo/internal/db"
 	"arcedo/cli-todo/internal/task"
 )
 
 func main() {
 	ctx := context.Background()
-	database, err := db.ConnectSqlite("cli-todo.db")
+	path, err := config.Path()
 	if err != nil {
-		log.Fatalf("failed to connect SQLite: %v", err)
+		log.Fatal(err)
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		// the defaults keep config edit working to fix the file
+		log.Printf("ignoring the config: %v", err)
+		cfg = config.Default()
+		cfg.Path = path
+	}
+	location, err := db.Locate(cli.DatabaseFlag(os.Args), cfg.DB)
+	if err != nil {
+		log.Printf("failed to locate the database: %v", err)
+		os.Exit(cli.ExitStorage)
+	}
+	database, err := db.ConnectSqlite(location.Path)
+	if err != nil {
+		log.Printf("failed to connect SQLite: %v", err)
+		os.Exit(cli.ExitStorage)
 	}
 	if err = db.Migrate(database); err != nil {
-		log.Fatalf("failed to migrate schema: %v", err)
+		log.Printf("failed to migrate schema: %v", err)
+		os.Exit(cli.ExitStorage)
 	}
 	repo := task.NewSqliteRepository(database)
 	service := task.NewService(repo)
 
-	cli := cli.New(service, os.Stdout, os.Stderr)
-	cli.Run(ctx, os.Args)
+	cli := cli.New(service, cfg, location, os.Stdout, os.Stderr)
+	os.Exit(cli.Run(ctx, os.Args))
 }